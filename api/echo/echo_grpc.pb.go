@@ -20,6 +20,11 @@ const _ = grpc.SupportPackageIsVersion7
 type EchoServiceClient interface {
 	PostEcho(ctx context.Context, in *EchoRequest, opts ...grpc.CallOption) (*EchoResponse, error)
 	GetEcho(ctx context.Context, in *EchoRequest, opts ...grpc.CallOption) (*EchoResponse, error)
+	// EchoStream repeats the echo count times, waiting interval between the messages
+	EchoStream(ctx context.Context, in *EchoStreamRequest, opts ...grpc.CallOption) (EchoService_EchoStreamClient, error)
+	// EchoChat echoes every request message back,
+	// the gateway serves it over a WebSocket
+	EchoChat(ctx context.Context, opts ...grpc.CallOption) (EchoService_EchoChatClient, error)
 }
 
 type echoServiceClient struct {
@@ -48,12 +53,80 @@ func (c *echoServiceClient) GetEcho(ctx context.Context, in *EchoRequest, opts .
 	return out, nil
 }
 
+func (c *echoServiceClient) EchoStream(ctx context.Context, in *EchoStreamRequest, opts ...grpc.CallOption) (EchoService_EchoStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &EchoService_ServiceDesc.Streams[0], "/echo.EchoService/EchoStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &echoServiceEchoStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EchoService_EchoStreamClient interface {
+	Recv() (*EchoResponse, error)
+	grpc.ClientStream
+}
+
+type echoServiceEchoStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *echoServiceEchoStreamClient) Recv() (*EchoResponse, error) {
+	m := new(EchoResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *echoServiceClient) EchoChat(ctx context.Context, opts ...grpc.CallOption) (EchoService_EchoChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &EchoService_ServiceDesc.Streams[1], "/echo.EchoService/EchoChat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &echoServiceEchoChatClient{stream}
+	return x, nil
+}
+
+type EchoService_EchoChatClient interface {
+	Send(*EchoRequest) error
+	Recv() (*EchoResponse, error)
+	grpc.ClientStream
+}
+
+type echoServiceEchoChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *echoServiceEchoChatClient) Send(m *EchoRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *echoServiceEchoChatClient) Recv() (*EchoResponse, error) {
+	m := new(EchoResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // EchoServiceServer is the server API for EchoService service.
 // All implementations must embed UnimplementedEchoServiceServer
 // for forward compatibility
 type EchoServiceServer interface {
 	PostEcho(context.Context, *EchoRequest) (*EchoResponse, error)
 	GetEcho(context.Context, *EchoRequest) (*EchoResponse, error)
+	// EchoStream repeats the echo count times, waiting interval between the messages
+	EchoStream(*EchoStreamRequest, EchoService_EchoStreamServer) error
+	// EchoChat echoes every request message back,
+	// the gateway serves it over a WebSocket
+	EchoChat(EchoService_EchoChatServer) error
 	mustEmbedUnimplementedEchoServiceServer()
 }
 
@@ -67,6 +140,12 @@ func (UnimplementedEchoServiceServer) PostEcho(context.Context, *EchoRequest) (*
 func (UnimplementedEchoServiceServer) GetEcho(context.Context, *EchoRequest) (*EchoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetEcho not implemented")
 }
+func (UnimplementedEchoServiceServer) EchoStream(*EchoStreamRequest, EchoService_EchoStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method EchoStream not implemented")
+}
+func (UnimplementedEchoServiceServer) EchoChat(EchoService_EchoChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method EchoChat not implemented")
+}
 func (UnimplementedEchoServiceServer) mustEmbedUnimplementedEchoServiceServer() {}
 
 // UnsafeEchoServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -116,6 +195,53 @@ func _EchoService_GetEcho_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EchoService_EchoStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EchoStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EchoServiceServer).EchoStream(m, &echoServiceEchoStreamServer{stream})
+}
+
+type EchoService_EchoStreamServer interface {
+	Send(*EchoResponse) error
+	grpc.ServerStream
+}
+
+type echoServiceEchoStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *echoServiceEchoStreamServer) Send(m *EchoResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _EchoService_EchoChat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EchoServiceServer).EchoChat(&echoServiceEchoChatServer{stream})
+}
+
+type EchoService_EchoChatServer interface {
+	Send(*EchoResponse) error
+	Recv() (*EchoRequest, error)
+	grpc.ServerStream
+}
+
+type echoServiceEchoChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *echoServiceEchoChatServer) Send(m *EchoResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *echoServiceEchoChatServer) Recv() (*EchoRequest, error) {
+	m := new(EchoRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // EchoService_ServiceDesc is the grpc.ServiceDesc for EchoService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -132,6 +258,18 @@ var EchoService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _EchoService_GetEcho_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "EchoStream",
+			Handler:       _EchoService_EchoStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "EchoChat",
+			Handler:       _EchoService_EchoChat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "echo/echo.proto",
 }
@@ -0,0 +1,8 @@
+package echo
+
+import _ "embed"
+
+// OpenAPISpec is the OpenAPI v2 document of EchoService generated by protoc-gen-openapiv2.
+//
+//go:embed echo.swagger.json
+var OpenAPISpec []byte
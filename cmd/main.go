@@ -2,21 +2,37 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"path"
 	"strings"
+	"syscall"
 	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	pb "github.com/akhripko/grpc-gateway/api/echo"
 	"github.com/akhripko/grpc-gateway/pkg"
@@ -34,20 +50,13 @@ func (s *server) PostEcho(ctx context.Context, in *pb.EchoRequest) (*pb.EchoResp
 	cookie := metadata.New(map[string]string{"set-cookie": "srvcookie:cookie_value"})
 	grpc.SetHeader(ctx, cookie)
 
+	// the caller is authenticated by the auth interceptor
 	var token string
-	headers, ok := metadata.FromIncomingContext(ctx)
-	if ok {
-		h := headers["my-header"]
-		if len(h) == 0 {
-			st, _ := status.New(codes.PermissionDenied, "my-proto-header was not provided").
-				WithDetails(&pb.Error{
-					Code:    403,
-					Message: "My-Header was not provided",
-				})
-			return &pb.EchoResponse{}, st.Err()
-		}
-		token = h[0]
-		log.Println("my-header: ", token)
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("my-header")) > 0 {
+		token = md.Get("my-header")[0]
+	}
+	if p := pkg.PrincipalFromContext(ctx); p != nil {
+		log.Println("caller: ", p.Subject, p.Verifier)
 	}
 	header := metadata.New(map[string]string{"my-srv-header": "srv-" + token})
 	grpc.SetHeader(ctx, header)
@@ -71,32 +80,305 @@ func (s *server) GetEcho(ctx context.Context, in *pb.EchoRequest) (*pb.EchoRespo
 	}, nil
 }
 
+// maxStreamCount and maxStreamInterval bound the EchoStream requests
+const (
+	maxStreamCount    = 100
+	maxStreamInterval = 10 * time.Second
+)
+
+func (s *server) EchoStream(in *pb.EchoStreamRequest, stream pb.EchoService_EchoStreamServer) error {
+	interval := in.Interval.AsDuration()
+	if in.Count < 1 || in.Count > maxStreamCount || interval < 0 || interval > maxStreamInterval {
+		st, _ := status.New(codes.InvalidArgument, "count or interval is out of range").
+			WithDetails(&pb.Error{
+				Code:    400,
+				Message: fmt.Sprintf("count must be in [1, %d] and interval in [0, %s]", maxStreamCount, maxStreamInterval),
+			})
+		return st.Err()
+	}
+
+	echo := in.GetEcho()
+	for i := int32(0); i < in.Count; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-stream.Context().Done():
+				return status.FromContextError(stream.Context().Err()).Err()
+			}
+		}
+		err := stream.Send(&pb.EchoResponse{
+			Name:    echo.GetName(),
+			Data1:   echo.GetData1(),
+			Data2:   echo.GetData2(),
+			EmId:    echo.GetEmId(),
+			BoolVal: echo.GetBoolVal(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) EchoChat(stream pb.EchoService_EchoChatServer) error {
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = stream.Send(&pb.EchoResponse{
+			Name:    in.Name,
+			Data1:   in.Data1,
+			Data2:   in.Data2,
+			EmId:    in.EmId,
+			BoolVal: in.BoolVal,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// inProcessServer runs the server interceptors for the calls made by the
+// gateway in the inprocess mode, where no gRPC server is involved.
+// Only the unary methods are wrapped, grpc-gateway doesn't call the
+// streaming ones in process and answers 501 Not Implemented on their routes.
+type inProcessServer struct {
+	pb.UnimplementedEchoServiceServer
+	srv pb.EchoServiceServer
+}
+
+func (s *inProcessServer) PostEcho(ctx context.Context, in *pb.EchoRequest) (*pb.EchoResponse, error) {
+	info := &grpc.UnaryServerInfo{Server: s.srv, FullMethod: echoFullMethods["PostEcho"]}
+	res, err := unaryInterceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.srv.PostEcho(ctx, req.(*pb.EchoRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*pb.EchoResponse), nil
+}
+
+func (s *inProcessServer) GetEcho(ctx context.Context, in *pb.EchoRequest) (*pb.EchoResponse, error) {
+	info := &grpc.UnaryServerInfo{Server: s.srv, FullMethod: echoFullMethods["GetEcho"]}
+	res, err := unaryInterceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.srv.GetEcho(ctx, req.(*pb.EchoRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*pb.EchoResponse), nil
+}
+
+// echoFullMethods are the full names of the unary EchoService methods, keyed by the method name
+var echoFullMethods = func() map[string]string {
+	desc := pb.EchoService_ServiceDesc
+	res := make(map[string]string, len(desc.Methods))
+	for _, m := range desc.Methods {
+		res[m.MethodName] = "/" + desc.ServiceName + "/" + m.MethodName
+	}
+	return res
+}()
+
+// unaryInterceptor is the interceptor chain of the gRPC server, built in main
+var unaryInterceptor grpc.UnaryServerInterceptor
+
 func main() {
-	// Create a listener on TCP port
-	lis, err := net.Listen("tcp", "0.0.0.0:8080") // nolint
+	authConfig := flag.String("auth-config", "", "path to the YAML or JSON auth config: verifiers and per-method policy")
+	rateLimitConfig := flag.String("ratelimit-config", "", "path to the YAML or JSON rate limit config of the gateway routes, no rate limiting without it")
+	corsOrigins := flag.String("cors-origins", "", "comma separated origins allowed to call the gateway from browsers, e.g. https://*.example.com, enables CORS")
+	corsMethods := flag.String("cors-methods", "GET,POST,PUT,PATCH,DELETE", "comma separated methods allowed for the CORS requests")
+	corsCredentials := flag.Bool("cors-credentials", false, "allow the CORS requests with cookies and authorization, not with the \"*\" origin")
+	corsMaxAge := flag.Duration("cors-max-age", 10*time.Minute, "how long browsers cache the CORS preflight responses")
+	bodyCaptureConfig := flag.String("body-capture-config", "", "path to the YAML or JSON config of the request and response payloads written to the logs, for debugging")
+	compressionMinSize := flag.Int("compression-min-size", 1024, "smallest gateway response compressed with br, zstd or gzip, -1 disables the compression")
+	maxDecodedSize := flag.Int64("max-decoded-body-size", pkg.DefaultMaxDecodedSize, "most bytes of a request body decoded from its Content-Encoding")
+	headersConfig := flag.String("headers-config", "", "path to the YAML or JSON header allow-list config, reloaded on SIGHUP")
+	logFormat := flag.String("log-format", "json", "access log format: json or logfmt")
+	singlePort := flag.Bool("single-port", false, "serve gRPC and the gateway on :8090, gRPC is routed by Content-Type")
+	dynamicBackends := flag.String("dynamic-backends", "", "comma separated gRPC backends whose services are discovered with server reflection and served by the gateway from their google.api.http annotations")
+	gatewayMode := flag.String("gateway-mode", "network", "how the gateway reaches the gRPC server: network, bufconn (in-memory listener) or inprocess (direct calls of the unary methods, the streaming routes answer 501)")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate of the gRPC and gateway listeners, enables TLS")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsCA := flag.String("tls-ca", "", "PEM CA bundle verifying client certificates and the gRPC server certificate")
+	tlsClientAuth := flag.Bool("tls-client-auth", false, "require client certificates signed by -tls-ca (mTLS)")
+	tlsServerName := flag.String("tls-server-name", "localhost", "name the gateway expects in the gRPC server certificate")
+	tlsReloadInterval := flag.Duration("tls-reload-interval", 30*time.Second, "how often the certificate files are checked for rotation")
+	adminAddr := flag.String("admin-addr", ":9090", "address of the admin listener serving /metrics")
+	grpcDebug := flag.String("grpc-debug", "off", "gRPC server reflection (v1 and v1alpha) and channelz: off, on (the gRPC listener) or admin (the admin listener only, describing the services of the gRPC listener)")
+	traceExporter := flag.String("trace-exporter", "none", "OpenTelemetry span exporter: none or stdout")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time to drain in-flight requests on SIGTERM")
+	shutdownDelay := flag.Duration("shutdown-delay", 0, "time to report not ready on SIGTERM before draining, lets load balancers stop sending requests")
+	flag.Parse()
+
+	var err error
+	if logger, err = pkg.NewLogger(os.Stdout, *logFormat); err != nil {
+		log.Fatalln("Failed to create logger:", err)
+	}
+
+	if *headersConfig != "" {
+		if err := headerMatchers.Load(*headersConfig); err != nil {
+			log.Fatalln("Failed to load headers config:", err)
+		}
+		reloadHeadersOnSIGHUP(*headersConfig)
+	}
+
+	authCfg := defaultAuth
+	if *authConfig != "" {
+		if authCfg, err = pkg.LoadAuthConfig(*authConfig); err != nil {
+			log.Fatalln("Failed to load auth config:", err)
+		}
+	}
+	auth, err := pkg.NewAuthenticator(authCfg)
 	if err != nil {
-		log.Fatalln("Failed to listen:", err)
+		log.Fatalln("Failed to create authenticator:", err)
 	}
 
-	// Create a gRPC server object
-	var s = grpc.NewServer(
-		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(addTraceIDUnaryInterceptor, LoggingUnaryInterceptor)),
+	if *bodyCaptureConfig != "" {
+		bodyCaptureCfg, err := pkg.LoadBodyCaptureConfig(*bodyCaptureConfig)
+		if err != nil {
+			log.Fatalln("Failed to load body capture config:", err)
+		}
+		if bodyCapture, err = pkg.NewBodyCapture(bodyCaptureCfg); err != nil {
+			log.Fatalln("Failed to create body capture:", err)
+		}
+	}
+
+	exporter, err := pkg.NewSpanExporter(*traceExporter, os.Stdout)
+	if err != nil {
+		log.Fatalln("Failed to create trace exporter:", err)
+	}
+	tp := pkg.NewTracerProvider(exporter)
+
+	metrics := pkg.NewMetrics()
+	recovery := pkg.NewRecovery(logger, metrics)
+
+	dialCreds := grpc.WithInsecure()
+	var tlsReloader *pkg.TLSReloader
+	// the client certificate subject is trusted from the gateway only
+	var isGateway func(*peer.Peer) bool
+	if *tlsCert != "" {
+		tlsReloader, err = pkg.NewTLSReloader(pkg.TLSFiles{
+			CertFile:   *tlsCert,
+			KeyFile:    *tlsKey,
+			CAFile:     *tlsCA,
+			ClientAuth: *tlsClientAuth,
+		})
+		if err != nil {
+			log.Fatalln("Failed to load TLS certificates:", err)
+		}
+		go tlsReloader.Watch(context.Background(), *tlsReloadInterval)
+		isGateway = tlsReloader.IsGateway
+		dialCreds = grpc.WithTransportCredentials(credentials.NewTLS(tlsReloader.ClientConfig(*tlsServerName)))
+	}
+
+	unaryInterceptor = grpc_middleware.ChainUnaryServer(
+		pkg.ClientCertUnaryServerInterceptor(isGateway),
+		pkg.TracingUnaryServerInterceptor(tp),
+		metrics.UnaryServerInterceptor,
+		addTraceIDUnaryInterceptor,
+		LoggingUnaryInterceptor,
+		recovery.UnaryServerInterceptor,
+		auth.UnaryServerInterceptor,
+		pkg.ValidationUnaryServerInterceptor,
 	)
-	pb.RegisterEchoServiceServer(s, &server{})
-	// Serve gRPC server
-	log.Println("Serving gRPC on 0.0.0.0:8080")
-	go func() {
-		log.Fatalln(s.Serve(lis))
-	}()
+	streamInterceptor := grpc_middleware.ChainStreamServer(
+		pkg.ClientCertStreamServerInterceptor(isGateway),
+		pkg.TracingStreamServerInterceptor(tp),
+		metrics.StreamServerInterceptor,
+		addTraceIDStreamInterceptor,
+		LoggingStreamInterceptor,
+		recovery.StreamServerInterceptor,
+		auth.StreamServerInterceptor,
+		pkg.ValidationStreamServerInterceptor,
+	)
+	serverOpts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(streamInterceptor),
+	}
+	if tlsReloader != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsReloader.ServerConfig())))
+	}
+
+	// Create a gRPC server object
+	var s = grpc.NewServer(serverOpts...)
+	srv := &server{}
+	pb.RegisterEchoServiceServer(s, srv)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	healthServer.SetServingStatus(pb.EchoService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	// adminGRPC serves the debug services on the admin listener
+	var adminGRPC *grpc.Server
+	switch *grpcDebug {
+	case "off":
+	case "on":
+		registerDebugServices(s, s)
+	case "admin":
+		// the admin listener is plaintext, it serves the debug services only,
+		// the reflection describes the services of the gRPC listener
+		adminGRPC = grpc.NewServer(grpc.UnaryInterceptor(unaryInterceptor), grpc.StreamInterceptor(streamInterceptor))
+		registerDebugServices(adminGRPC, s)
+	default:
+		log.Fatalln("Unknown grpc debug mode:", *grpcDebug)
+	}
+
+	backendAddr := "0.0.0.0:8080"
+	tracingDialOpts := []grpc.DialOption{
+		grpc.WithUnaryInterceptor(pkg.TracingUnaryClientInterceptor(tp)),
+		grpc.WithStreamInterceptor(pkg.TracingStreamClientInterceptor(tp)),
+	}
+	dialOpts := append([]grpc.DialOption{dialCreds}, tracingDialOpts...)
+	if *singlePort {
+		// the gateway talks to the port it serves itself,
+		// so it can't wait for the connection before serving
+		backendAddr = "0.0.0.0:8090"
+	} else {
+		// Create a listener on TCP port
+		lis, err := net.Listen("tcp", backendAddr) // nolint
+		if err != nil {
+			log.Fatalln("Failed to listen:", err)
+		}
+		// Serve gRPC server
+		log.Println("Serving gRPC on 0.0.0.0:8080")
+		go func() {
+			// Serve returns nil after GracefulStop
+			if err := s.Serve(lis); err != nil {
+				log.Fatalln(err)
+			}
+		}()
+		dialOpts = append(dialOpts, grpc.WithBlock())
+	}
 
 	// Create a client connection to the gRPC server we just started
 	// This is where the gRPC-Gateway proxies the requests
-	conn, err := grpc.DialContext(
-		context.Background(),
-		"0.0.0.0:8080",
-		grpc.WithBlock(),
-		grpc.WithInsecure(),
-	)
+	var conn *grpc.ClientConn
+	switch *gatewayMode {
+	case "network":
+		conn, err = grpc.DialContext(context.Background(), backendAddr, dialOpts...)
+	case "bufconn":
+		// in-memory connection, the server interceptors run as usual
+		bufLis := bufconn.Listen(1 << 20)
+		go func() {
+			if err := s.Serve(bufLis); err != nil {
+				log.Fatalln(err)
+			}
+		}()
+		bufDialOpts := append([]grpc.DialOption{
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return bufLis.Dial()
+			}),
+			dialCreds,
+		}, tracingDialOpts...)
+		conn, err = grpc.DialContext(context.Background(), "bufnet", bufDialOpts...)
+	case "inprocess":
+		// no connection, the gateway calls the server implementation
+	default:
+		log.Fatalln("Unknown gateway mode:", *gatewayMode)
+	}
 	if err != nil {
 		log.Fatalln("Failed to dial server:", err)
 	}
@@ -104,57 +386,287 @@ func main() {
 	gwmux := runtime.NewServeMux(
 		runtime.WithIncomingHeaderMatcher(IncomingHeaderMatcher),
 		runtime.WithOutgoingHeaderMatcher(OutgoingHeaderMatcher),
-		runtime.WithErrorHandler(ErrorHandler))
-
-	withLogging := pkg.WithLoggingMiddleware(gwmux)
+		runtime.WithErrorHandler(ErrorHandler),
+		runtime.WithStreamErrorHandler(StreamErrorHandler),
+		// streams are newline delimited JSON or server-sent events when the client asks for them
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, jsonMarshaler),
+		runtime.WithMarshalerOption(pkg.NDJSONContentType, &pkg.NDJSONMarshaler{Marshaler: jsonMarshaler}),
+		runtime.WithMarshalerOption(pkg.SSEContentType, &pkg.SSEMarshaler{Marshaler: jsonMarshaler}),
+		runtime.WithMetadata(pkg.RouteAnnotator),
+		runtime.WithMetadata(pkg.ClientCertAnnotator))
+
+	// bidi streaming routes are served over WebSockets
+	withWebSocket := pkg.WithWebSocketProxy(gwmux, logger, nil)
+
+	// the middlewares reply with the errors of ErrorHandler as well
+	httpError := func(w http.ResponseWriter, r *http.Request, err error) {
+		runtime.HTTPError(r.Context(), gwmux, jsonMarshaler, w, r, err)
+	}
+	// the routes are not limited without a rate limit config
+	withRateLimit := withWebSocket
+	if *rateLimitConfig != "" {
+		rateLimitCfg, err := pkg.LoadRateLimitConfig(*rateLimitConfig)
+		if err != nil {
+			log.Fatalln("Failed to load rate limit config:", err)
+		}
+		if withRateLimit, err = pkg.WithRateLimitMiddleware(withWebSocket, rateLimitCfg, pkg.NewMemoryRateLimitStore(), auth, httpError, logger); err != nil {
+			log.Fatalln("Failed to create rate limiter:", err)
+		}
+	}
+	withRecovery := recovery.Middleware(withRateLimit, httpError)
+	withBodyCapture := withRecovery
+	if bodyCapture != nil {
+		withBodyCapture = pkg.WithBodyCaptureMiddleware(withRecovery, bodyCapture)
+	}
+	withCompression := withBodyCapture
+	if *compressionMinSize >= 0 {
+		withCompression = pkg.WithCompressionMiddleware(withBodyCapture, pkg.CompressionConfig{MinSize: *compressionMinSize, MaxDecodedSize: *maxDecodedSize}, httpError)
+	}
+	withLogging := pkg.WithTracingMiddleware(
+		pkg.WithMetricsMiddleware(pkg.WithLoggingMiddleware(withCompression, logger), metrics), tp)
 
 	// Register Greeter
-	err = pb.RegisterEchoServiceHandler(context.Background(), gwmux, conn)
+	if conn != nil {
+		err = pb.RegisterEchoServiceHandler(context.Background(), gwmux, conn)
+	} else {
+		err = pb.RegisterEchoServiceHandlerServer(context.Background(), gwmux, &inProcessServer{srv: srv})
+	}
 	if err != nil {
 		log.Fatalln("Failed to register gateway:", err)
 	}
+	var dynamicConns []*grpc.ClientConn
+	if *dynamicBackends != "" {
+		for _, addr := range strings.Split(*dynamicBackends, ",") {
+			dynamicConn := registerDynamicBackend(gwmux, strings.TrimSpace(addr), append([]grpc.DialOption{dialCreds}, tracingDialOpts...))
+			dynamicConns = append(dynamicConns, dynamicConn)
+		}
+	}
+
+	healthChecker := pkg.NewHealthChecker(healthServer, conn, pb.EchoService_ServiceDesc.ServiceName)
+	withLogging = pkg.WithHealthHandlers(withLogging, healthChecker)
+	withLogging = pkg.WithOpenAPIHandlers(withLogging, pb.OpenAPISpec)
+	if *corsOrigins != "" {
+		// the allowed and exposed headers follow the header allow-lists
+		withLogging, err = pkg.WithCORSMiddleware(withLogging, pkg.CORSConfig{
+			AllowedOrigins:   strings.Split(*corsOrigins, ","),
+			AllowedMethods:   strings.Split(*corsMethods, ","),
+			AllowCredentials: *corsCredentials,
+			MaxAge:           *corsMaxAge,
+		}, headerMatchers)
+		if err != nil {
+			log.Fatalln("Failed to create CORS middleware:", err)
+		}
+	}
 
 	gwServer := &http.Server{
 		Addr:    ":8090",
 		Handler: withLogging,
 	}
+	scheme := "http"
+	if tlsReloader != nil {
+		gwServer.TLSConfig = tlsReloader.ServerConfig()
+		scheme = "https"
+	}
+	// singlePortHandler serves the gRPC calls on :8090 in the single port mode
+	var singlePortHandler *pkg.SinglePort
+	if *singlePort {
+		singlePortHandler = pkg.NewSinglePort(s, withLogging)
+		gwServer.Handler = singlePortHandler
+		log.Println("Serving gRPC and gRPC-Gateway on " + scheme + "://0.0.0.0:8090")
+	} else {
+		log.Println("Serving gRPC-Gateway on " + scheme + "://0.0.0.0:8090")
+	}
+	go func() {
+		var err error
+		if tlsReloader != nil {
+			// the certificates come from TLSConfig
+			err = gwServer.ListenAndServeTLS("", "")
+		} else {
+			err = gwServer.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			log.Fatalln(err)
+		}
+	}()
 
-	log.Println("Serving gRPC-Gateway on http://0.0.0.0:8090")
-	log.Fatalln(gwServer.ListenAndServe())
-}
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", metrics.Handler())
+	adminServer := &http.Server{
+		Addr:    *adminAddr,
+		Handler: adminMux,
+	}
+	if adminGRPC != nil {
+		// plaintext gRPC (h2c) next to /metrics
+		adminServer.Handler = pkg.NewSinglePort(adminGRPC, adminMux)
+	}
+	log.Println("Serving admin endpoints on " + *adminAddr)
+	go func() {
+		if err := adminServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalln(err)
+		}
+	}()
 
-type void struct{}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	log.Println("Shutting down on", <-stop)
 
-var member = void{}
+	// load balancers see the gateway not ready while it drains
+	healthChecker.Shutdown()
+	time.Sleep(*shutdownDelay)
 
-var outgoingHeaders = map[string]void{
-	"set-cookie":    member,
-	"x-trace-id":    member,
-	"my-srv-header": member,
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	shutdown(ctx, gwServer, s, conn, singlePortHandler)
+	for _, dynamicConn := range dynamicConns {
+		if err := dynamicConn.Close(); err != nil {
+			log.Println("failed to close dynamic backend connection:", err)
+		}
+	}
+	// metrics stay available while draining
+	if err := adminServer.Close(); err != nil {
+		log.Println("failed to close admin server:", err)
+	}
+	if adminGRPC != nil {
+		adminGRPC.Stop()
+	}
+	if exporter != nil {
+		if err := tp.Shutdown(ctx); err != nil {
+			log.Println("failed to flush spans:", err)
+		}
+	}
 }
 
-func OutgoingHeaderMatcher(key string) (string, bool) {
-	if key == "grpcgateway-content-type" {
-		return "", false
+// registerDebugServices registers the server reflection of the services of
+// described, for grpcurl and grpcui, and channelz, which reports the gRPC
+// server and the client connections of the gateway.
+func registerDebugServices(s *grpc.Server, described reflection.ServiceInfoProvider) {
+	pkg.RegisterReflection(s, described)
+	channelz.RegisterChannelzServiceToServer(s)
+}
+
+// registerDynamicBackend serves the services of the backend found with server
+// reflection, except the ones served by the generated handlers. The services
+// are discovered once at startup.
+func registerDynamicBackend(gwmux *runtime.ServeMux, addr string, dialOpts []grpc.DialOption) *grpc.ClientConn {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dynamicConn, err := grpc.DialContext(ctx, addr, dialOpts...)
+	if err != nil {
+		log.Fatalln("Failed to dial dynamic backend:", err)
+	}
+	services, err := pkg.DiscoverServices(ctx, dynamicConn)
+	if err != nil {
+		log.Fatalln("Failed to discover services of "+addr+":", err)
+	}
+	var dynamic []protoreflect.ServiceDescriptor
+	for _, sd := range services {
+		if sd.FullName() == protoreflect.FullName(pb.EchoService_ServiceDesc.ServiceName) {
+			continue
+		}
+		dynamic = append(dynamic, sd)
+	}
+	if err := pkg.RegisterDynamicHandlers(gwmux, dynamicConn, dynamic, logger); err != nil {
+		log.Fatalln("Failed to register dynamic backend "+addr+":", err)
+	}
+	return dynamicConn
+}
+
+// shutdown drains the gateway first, its requests still need the gRPC server,
+// and the gRPC server after it. Both drains share the ctx deadline, the
+// connections left when it expires are closed. The gRPC calls served by
+// singlePort are drained by it, GracefulStop panics on them.
+func shutdown(ctx context.Context, gwServer *http.Server, s *grpc.Server, conn *grpc.ClientConn, singlePort *pkg.SinglePort) {
+	start := time.Now()
+
+	httpErr := gwServer.Shutdown(ctx)
+	if httpErr != nil {
+		_ = gwServer.Close()
+	}
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			log.Println("failed to close gateway client connection:", err)
+		}
 	}
-	lower := strings.ToLower(key)
-	if _, ok := outgoingHeaders[lower]; ok {
-		return lower, true
+
+	grpcDrained := true
+	if singlePort != nil {
+		grpcDrained = singlePort.Drain(ctx)
+		s.Stop()
+	} else {
+		stopped := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcDrained = false
+			s.Stop()
+			<-stopped
+		}
 	}
-	return runtime.DefaultHeaderMatcher(lower)
+
+	logger.Info("shutdown",
+		"http_drained", httpErr == nil,
+		"grpc_drained", grpcDrained,
+		"duration", time.Since(start),
+	)
 }
 
-var incomingHeaders = map[string]void{
-	"x-trace-id": member,
-	"my-header":  member,
+// logger writes the access logs of both the gateway and the gRPC server
+var logger pkg.Logger = pkg.NewJSONLogger(os.Stdout)
+
+// defaultHeaders is used when no headers config file is given
+var defaultHeaders = pkg.HeadersConfig{
+	Incoming: pkg.HeaderRules{
+		Allow: []string{"x-trace-id", "my-header"},
+	},
+	Outgoing: pkg.HeaderRules{
+		Allow: []string{"set-cookie", "x-trace-id", "my-srv-header"},
+	},
 }
 
-func IncomingHeaderMatcher(key string) (string, bool) {
-	lower := strings.ToLower(key)
-	if _, ok := incomingHeaders[lower]; ok {
-		return lower, true
+var headerMatchers = pkg.NewHeaderMatchers(defaultHeaders)
+
+// bodyCapture picks the calls with the payloads logged, nil captures nothing
+var bodyCapture *pkg.BodyCapture
+
+// defaultAuth is used when no auth config file is given,
+// PostEcho requires any My-Header value
+var defaultAuth = func() pkg.AuthConfig {
+	var cfg pkg.AuthConfig
+	cfg.APIKeys.Header = "my-header"
+	cfg.APIKeys.AllowAny = true
+	cfg.Default.Public = true
+	cfg.Methods = map[string]pkg.AuthRule{
+		"/echo.EchoService/PostEcho": {},
 	}
-	return runtime.DefaultHeaderMatcher(key)
+	return cfg
+}()
+
+func OutgoingHeaderMatcher(key string) (string, bool) {
+	return headerMatchers.Outgoing(key)
+}
+
+func IncomingHeaderMatcher(key string) (string, bool) {
+	return headerMatchers.Incoming(key)
+}
+
+// reloadHeadersOnSIGHUP re-reads the headers config on SIGHUP,
+// the gateway keeps serving with the previous config when the file is broken
+func reloadHeadersOnSIGHUP(path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := headerMatchers.Load(path); err != nil {
+				log.Println("failed to reload headers config:", err)
+				continue
+			}
+			log.Println("headers config reloaded from", path)
+		}
+	}()
 }
 
 type Error struct {
@@ -162,6 +674,22 @@ type Error struct {
 	Message string
 }
 
+// jsonMarshaler is the default marshaler of the gateway
+var jsonMarshaler = &runtime.HTTPBodyMarshaler{
+	Marshaler: &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			EmitUnpopulated: true,
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{
+			DiscardUnknown: true,
+		},
+	},
+}
+
+// statusMapper picks the HTTP status of the error responses,
+// per service overrides are registered here
+var statusMapper = pkg.NewStatusMapper()
+
 func ErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
 	// return Internal when Marshal failed
 	const fallback = `{"code": 13, "message": "failed to marshal error message"}`
@@ -169,21 +697,47 @@ func ErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.
 	st, _ := status.FromError(err)
 	errMsg := StatusToError(st)
 
+	rpcMethod, _ := runtime.RPCMethod(ctx)
+	httpStatus := statusMapper.HTTPStatus(rpcMethod, st)
+	errMsg.Code = int32(httpStatus)
+
 	md, ok := runtime.ServerMetadataFromContext(ctx)
 	if !ok {
 		grpclog.Infof("Failed to extract ServerMetadata from context")
 	}
 	if md.HeaderMD != nil {
-		trID := md.HeaderMD.Get("x-trace-id")
+		trID := md.HeaderMD.Get(pkg.TraceIDHeader)
 		if len(trID) > 0 {
 			errMsg.TraceID = trID[0]
 		}
 	}
 	handleForwardResponseServerMetadata(w, md)
+	// the request failed before reaching the gRPC server
+	if errMsg.TraceID == "" {
+		if id := pkg.TraceIDFromContext(r.Context()); id != "" {
+			errMsg.TraceID = id
+			w.Header().Set(pkg.TraceIDHeader, id)
+		}
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	buf, merr := marshaler.Marshal(errMsg)
+	if retryAfter := statusMapper.RetryAfter(st); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+
+	// the client picks the error format with the Accept header
+	var (
+		buf  []byte
+		merr error
+	)
+	if pkg.AcceptsProblem(r) {
+		problem := pkg.NewProblem(st, httpStatus, r.URL.RequestURI())
+		problem.TraceID = errMsg.TraceID
+		w.Header().Set("Content-Type", pkg.ProblemContentType)
+		buf, merr = json.Marshal(problem)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		buf, merr = marshaler.Marshal(errMsg)
+	}
 	if merr != nil {
 		log.Println("failed to marshal error message: ", merr)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -193,11 +747,32 @@ func ErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.
 		return
 	}
 
+	w.WriteHeader(httpStatus)
 	if _, err := w.Write(buf); err != nil {
 		grpclog.Infof("failed to write response: %v", err)
 	}
 }
 
+// StreamErrorHandler puts the pb.Error rendered by ErrorHandler into the
+// status, the stream marshalers write it as the error chunk
+func StreamErrorHandler(ctx context.Context, err error) *status.Status {
+	st := status.Convert(err)
+	errMsg := StatusToError(st)
+
+	rpcMethod, _ := runtime.RPCMethod(ctx)
+	errMsg.Code = int32(statusMapper.HTTPStatus(rpcMethod, st))
+	if errMsg.TraceID == "" {
+		errMsg.TraceID = pkg.TraceIDFromContext(ctx)
+	}
+
+	res, derr := status.New(st.Code(), st.Message()).WithDetails(errMsg)
+	if derr != nil {
+		log.Println("failed to build stream error: ", derr)
+		return st
+	}
+	return res
+}
+
 func handleForwardResponseServerMetadata(w http.ResponseWriter, md runtime.ServerMetadata) {
 	for k, vs := range md.HeaderMD {
 		if h, ok := OutgoingHeaderMatcher(k); ok {
@@ -212,29 +787,63 @@ func StatusToError(st *status.Status) *pb.Error {
 	if st == nil {
 		return nil
 	}
-	details := st.Details()
-	if len(details) != 0 {
-		if res, ok := details[0].(*pb.Error); ok {
-			return res
+	res := &pb.Error{Code: 500, Message: st.Message()}
+	var violations []*pb.FieldViolation
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *pb.Error:
+			res = d
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				violations = append(violations, &pb.FieldViolation{Field: v.Field, Description: v.Description})
+			}
 		}
 	}
-	return &pb.Error{Code: 500, Message: st.Message()}
+	if len(res.Violations) == 0 {
+		res.Violations = violations
+	}
+	return res
 }
 
 func addTraceIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	// get id from metadata
-	id, ok := ReadMetadataValue(ctx, "x-trace-id")
-	if !ok {
-		return handler(ctx, req)
-	}
+	id := resolveTraceID(ctx)
+	ctx = pkg.ContextWithTraceID(ctx, id)
 	// set atomic trace id header
 	grpc.SetHeader(ctx, metadata.New(map[string]string{
-		"x-trace-id": id,
+		pkg.TraceIDHeader: id,
 	}))
 
 	return handler(ctx, req)
 }
 
+func addTraceIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := resolveTraceID(ss.Context())
+	// set trace id header, it is sent with the first message
+	if err := ss.SetHeader(metadata.New(map[string]string{
+		pkg.TraceIDHeader: id,
+	})); err != nil {
+		log.Println("failed to set trace id header:", err)
+	}
+
+	wrapped := grpc_middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = pkg.ContextWithTraceID(ss.Context(), id)
+	return handler(srv, wrapped)
+}
+
+// resolveTraceID returns the trace id sent by the client,
+// the one of the in-process gateway or a new one
+func resolveTraceID(ctx context.Context) string {
+	// get id from metadata, the client may not send it
+	id, _ := ReadMetadataValue(ctx, pkg.TraceIDHeader)
+	if id == "" {
+		id = pkg.TraceIDFromContext(ctx)
+	}
+	if id == "" {
+		id = pkg.NewTraceID(ctx)
+	}
+	return id
+}
+
 func ReadMetadataValue(ctx context.Context, key string) (string, bool) {
 	// get metadata from context
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -251,9 +860,9 @@ func ReadMetadataValue(ctx context.Context, key string) (string, bool) {
 
 func LoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	start := time.Now()
-	// get id from metadata
-	traceID, ok := ReadMetadataValue(ctx, "x-trace-id")
-	if !ok {
+	// get id set by addTraceIDUnaryInterceptor
+	traceID := pkg.TraceIDFromContext(ctx)
+	if traceID == "" {
 		traceID = "N/A"
 	}
 	// call handler
@@ -264,13 +873,94 @@ func LoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.Un
 	service := path.Dir(info.FullMethod)[1:]
 	method := path.Base(info.FullMethod)
 
-	log.Println("grpc trace-id", traceID)
-	log.Println("grpc method", info.FullMethod)
-	log.Println("grpc service", service)
-	log.Println("grpc method", method)
-	log.Println("grpc latency", time.Since(start))
-	log.Println("grpc status", st.Code().String())
-	log.Println("grpc error", st.Message())
+	keyvals := []interface{}{
+		"trace_id", traceID,
+		"full_method", info.FullMethod,
+		"service", service,
+		"method", method,
+		"latency", time.Since(start),
+		"code", st.Code().String(),
+		"error", st.Message(),
+	}
+	if bodyCapture.CaptureMethod(info.FullMethod) {
+		request, response := bodyCapture.NewPayloadCapture(), bodyCapture.NewPayloadCapture()
+		request.Add(req)
+		response.Add(res)
+		keyvals = append(keyvals, "request", request, "response", response)
+	}
+	logger.Info("grpc request", keyvals...)
 
 	return res, err
 }
+
+// countingServerStream counts the stream messages for the logs,
+// and captures them when requests and responses are set
+type countingServerStream struct {
+	grpc.ServerStream
+	sent      int
+	received  int
+	requests  *pkg.PayloadCapture
+	responses *pkg.PayloadCapture
+}
+
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		if s.responses != nil {
+			s.responses.Add(m)
+		}
+	}
+	return err
+}
+
+func (s *countingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		if s.requests != nil {
+			s.requests.Add(m)
+		}
+	}
+	return err
+}
+
+func LoggingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	// get id set by addTraceIDStreamInterceptor
+	traceID := pkg.TraceIDFromContext(ss.Context())
+	if traceID == "" {
+		traceID = "N/A"
+	}
+	// call handler
+	counting := &countingServerStream{ServerStream: ss}
+	if bodyCapture.CaptureMethod(info.FullMethod) {
+		counting.requests, counting.responses = bodyCapture.NewPayloadCapture(), bodyCapture.NewPayloadCapture()
+	}
+	err := handler(srv, counting)
+	// build log record
+	st, _ := status.FromError(err)
+
+	service := path.Dir(info.FullMethod)[1:]
+	method := path.Base(info.FullMethod)
+
+	keyvals := []interface{}{
+		"trace_id", traceID,
+		"full_method", info.FullMethod,
+		"service", service,
+		"method", method,
+		"client_stream", info.IsClientStream,
+		"server_stream", info.IsServerStream,
+		"msgs_received", counting.received,
+		"msgs_sent", counting.sent,
+		"duration", time.Since(start),
+		"code", st.Code().String(),
+		"error", st.Message(),
+	}
+	if counting.requests != nil {
+		keyvals = append(keyvals, "request", counting.requests, "response", counting.responses)
+	}
+	logger.Info("grpc stream", keyvals...)
+
+	return err
+}
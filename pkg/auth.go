@@ -0,0 +1,388 @@
+package pkg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// ErrNoCredentials is returned by a Verifier when the request carries no
+// credentials of its kind, the next verifier is tried then.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string
+	// Verifier names the verifier which accepted the credentials:
+	// "api-key", "hmac" or "jwt"
+	Verifier string
+	Scopes   []string
+}
+
+// HasScopes reports whether the principal was granted all the scopes.
+func (p *Principal) HasScopes(scopes []string) bool {
+	for _, want := range scopes {
+		found := false
+		for _, s := range p.Scopes {
+			if s == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal stores the authenticated caller.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, it is nil when
+// the method is public and the request had no credentials.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Verifier authenticates the request by its metadata.
+type Verifier interface {
+	Verify(md metadata.MD) (*Principal, error)
+}
+
+// authorization returns the credentials of the authorization metadata
+// with the scheme, e.g. "Bearer", and ErrNoCredentials for other schemes.
+func authorization(md metadata.MD, scheme string) (string, error) {
+	for _, v := range md.Get("authorization") {
+		if len(v) > len(scheme) && strings.EqualFold(v[:len(scheme)], scheme) && v[len(scheme)] == ' ' {
+			return strings.TrimSpace(v[len(scheme)+1:]), nil
+		}
+	}
+	return "", ErrNoCredentials
+}
+
+// APIKey is a static key of a caller.
+type APIKey struct {
+	Key     string   `yaml:"key" json:"key"`
+	Subject string   `yaml:"subject" json:"subject"`
+	Scopes  []string `yaml:"scopes" json:"scopes"`
+}
+
+// APIKeyVerifier accepts the keys sent in a metadata header.
+type APIKeyVerifier struct {
+	header   string
+	keys     []APIKey
+	allowAny bool
+}
+
+// AnonymousSubject is the subject of the callers accepted by allowAny.
+const AnonymousSubject = "anonymous"
+
+// NewAPIKeyVerifier checks the header against keys. allowAny accepts any
+// non-empty value as an anonymous caller, it is meant for development.
+func NewAPIKeyVerifier(header string, keys []APIKey, allowAny bool) *APIKeyVerifier {
+	return &APIKeyVerifier{header: strings.ToLower(header), keys: keys, allowAny: allowAny}
+}
+
+func (v *APIKeyVerifier) Verify(md metadata.MD) (*Principal, error) {
+	values := md.Get(v.header)
+	if len(values) == 0 || values[0] == "" {
+		return nil, ErrNoCredentials
+	}
+	for _, k := range v.keys {
+		if subtle.ConstantTimeCompare([]byte(values[0]), []byte(k.Key)) == 1 {
+			return &Principal{Subject: k.Subject, Verifier: "api-key", Scopes: k.Scopes}, nil
+		}
+	}
+	if v.allowAny {
+		return &Principal{Subject: AnonymousSubject, Verifier: "api-key"}, nil
+	}
+	return nil, errors.New("unknown api key")
+}
+
+// HMACVerifier accepts the "HMAC <subject>.<expiry>.<signature>" authorization,
+// see SignHMACToken. The callers get the scopes of the verifier.
+type HMACVerifier struct {
+	secret []byte
+	scopes []string
+	now    func() time.Time
+}
+
+func NewHMACVerifier(secret []byte, scopes []string) *HMACVerifier {
+	return &HMACVerifier{secret: secret, scopes: scopes, now: time.Now}
+}
+
+func hmacSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignHMACToken returns the token of subject valid until expiry. The
+// signature is the unpadded base64url HMAC-SHA256 of "<subject>.<expiry>",
+// expiry is in Unix seconds.
+func SignHMACToken(secret []byte, subject string, expiry time.Time) string {
+	payload := subject + "." + strconv.FormatInt(expiry.Unix(), 10)
+	return payload + "." + hmacSignature(secret, payload)
+}
+
+func (v *HMACVerifier) Verify(md metadata.MD) (*Principal, error) {
+	token, err := authorization(md, "HMAC")
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return nil, errors.New("malformed hmac token")
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(hmacSignature(v.secret, payload))) {
+		return nil, errors.New("invalid hmac token signature")
+	}
+	j := strings.LastIndexByte(payload, '.')
+	if j < 0 {
+		return nil, errors.New("malformed hmac token")
+	}
+	expiry, err := strconv.ParseInt(payload[j+1:], 10, 64)
+	if err != nil {
+		return nil, errors.New("malformed hmac token expiry")
+	}
+	if v.now().Unix() >= expiry {
+		return nil, errors.New("hmac token expired")
+	}
+	return &Principal{Subject: payload[:j], Verifier: "hmac", Scopes: v.scopes}, nil
+}
+
+// JWTVerifier accepts the "Bearer <jwt>" authorization signed by a key of
+// a JWKS with an exp claim. The scopes come from the space separated "scope" claim.
+type JWTVerifier struct {
+	keys     jose.JSONWebKeySet
+	expected jwt.Expected
+}
+
+// NewJWTVerifier loads the JWKS file, the issuer and the audience are
+// checked when they are not empty.
+func NewJWTVerifier(jwksFile, issuer, audience string) (*JWTVerifier, error) {
+	data, err := ioutil.ReadFile(jwksFile)
+	if err != nil {
+		return nil, err
+	}
+	v := &JWTVerifier{expected: jwt.Expected{Issuer: issuer}}
+	if err := json.Unmarshal(data, &v.keys); err != nil {
+		return nil, fmt.Errorf("parse jwks %s: %w", jwksFile, err)
+	}
+	if len(v.keys.Keys) == 0 {
+		return nil, fmt.Errorf("no keys in jwks %s", jwksFile)
+	}
+	if audience != "" {
+		v.expected.Audience = jwt.Audience{audience}
+	}
+	return v, nil
+}
+
+func (v *JWTVerifier) Verify(md metadata.MD) (*Principal, error) {
+	raw, err := authorization(md, "Bearer")
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt: %w", err)
+	}
+	if len(token.Headers) == 0 {
+		return nil, errors.New("malformed jwt")
+	}
+	keys := v.keys.Key(token.Headers[0].KeyID)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("unknown jwt key id %q", token.Headers[0].KeyID)
+	}
+	var claims jwt.Claims
+	var extra struct {
+		Scope string `json:"scope"`
+	}
+	if err := token.Claims(keys[0].Public(), &claims, &extra); err != nil {
+		return nil, fmt.Errorf("invalid jwt: %w", err)
+	}
+	// ValidateWithLeeway skips the missing exp, the tokens never expiring are refused
+	if claims.Expiry == nil {
+		return nil, errors.New("invalid jwt claims: no expiration time (exp)")
+	}
+	expected := v.expected.WithTime(time.Now())
+	if err := claims.ValidateWithLeeway(expected, jwt.DefaultLeeway); err != nil {
+		return nil, fmt.Errorf("invalid jwt claims: %w", err)
+	}
+	return &Principal{Subject: claims.Subject, Verifier: "jwt", Scopes: strings.Fields(extra.Scope)}, nil
+}
+
+// AuthRule is the policy of a method. The zero rule requires any
+// authenticated caller.
+type AuthRule struct {
+	// Public methods serve the callers without credentials as well
+	Public bool `yaml:"public" json:"public"`
+	// Scopes must all be granted to the caller, 403 otherwise
+	Scopes []string `yaml:"scopes" json:"scopes"`
+}
+
+// AuthConfig configures the verifiers and the per-method policy.
+// The verifiers are tried in the order api keys, HMAC, JWT.
+type AuthConfig struct {
+	APIKeys struct {
+		Header string   `yaml:"header" json:"header"`
+		Keys   []APIKey `yaml:"keys" json:"keys"`
+		// AllowAny accepts any value of Header, for development only
+		AllowAny bool `yaml:"allow_any" json:"allow_any"`
+	} `yaml:"api_keys" json:"api_keys"`
+	HMAC struct {
+		Secret string   `yaml:"secret" json:"secret"`
+		Scopes []string `yaml:"scopes" json:"scopes"`
+	} `yaml:"hmac" json:"hmac"`
+	JWT struct {
+		JWKSFile string `yaml:"jwks_file" json:"jwks_file"`
+		Issuer   string `yaml:"issuer" json:"issuer"`
+		Audience string `yaml:"audience" json:"audience"`
+	} `yaml:"jwt" json:"jwt"`
+	// Default is the rule of the methods missing in Methods
+	Default AuthRule `yaml:"default" json:"default"`
+	// Methods are keyed by the full method, e.g. "/echo.EchoService/PostEcho",
+	// or by the service, e.g. "/grpc.health.v1.Health/*"
+	Methods map[string]AuthRule `yaml:"methods" json:"methods"`
+}
+
+// LoadAuthConfig reads the auth config from a YAML or JSON file.
+func LoadAuthConfig(file string) (AuthConfig, error) {
+	var cfg AuthConfig
+	if err := loadConfig(file, &cfg); err != nil {
+		return cfg, fmt.Errorf("load auth config %s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+// Authenticator authenticates the gRPC calls and applies the per-method policy.
+type Authenticator struct {
+	verifiers []Verifier
+	rules     map[string]AuthRule
+	def       AuthRule
+}
+
+// NewAuthenticator creates the verifiers configured in cfg.
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{rules: cfg.Methods, def: cfg.Default}
+	if cfg.APIKeys.Header != "" {
+		a.verifiers = append(a.verifiers, NewAPIKeyVerifier(cfg.APIKeys.Header, cfg.APIKeys.Keys, cfg.APIKeys.AllowAny))
+	}
+	if cfg.HMAC.Secret != "" {
+		a.verifiers = append(a.verifiers, NewHMACVerifier([]byte(cfg.HMAC.Secret), cfg.HMAC.Scopes))
+	}
+	if cfg.JWT.JWKSFile != "" {
+		v, err := NewJWTVerifier(cfg.JWT.JWKSFile, cfg.JWT.Issuer, cfg.JWT.Audience)
+		if err != nil {
+			return nil, err
+		}
+		a.verifiers = append(a.verifiers, v)
+	}
+	return a, nil
+}
+
+// NewAuthenticatorFromVerifiers is NewAuthenticator with custom verifiers.
+func NewAuthenticatorFromVerifiers(verifiers []Verifier, def AuthRule, methods map[string]AuthRule) *Authenticator {
+	return &Authenticator{verifiers: verifiers, rules: methods, def: def}
+}
+
+func (a *Authenticator) rule(fullMethod string) AuthRule {
+	if r, ok := a.rules[fullMethod]; ok {
+		return r
+	}
+	if r, ok := a.rules[path.Dir(fullMethod)+"/*"]; ok {
+		return r
+	}
+	return a.def
+}
+
+func authError(code codes.Code, httpStatus int32, msg string) error {
+	st, err := status.New(code, msg).WithDetails(&pb.Error{Code: httpStatus, Message: msg})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
+
+// Verify returns the principal of the first verifier finding credentials
+// of its kind in md, or ErrNoCredentials when none does.
+func (a *Authenticator) Verify(md metadata.MD) (*Principal, error) {
+	for _, v := range a.verifiers {
+		p, err := v.Verify(md)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// Authenticate returns the context with the principal of the call,
+// or Unauthenticated (401) and PermissionDenied (403) errors.
+func (a *Authenticator) Authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	rule := a.rule(fullMethod)
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	principal, err := a.Verify(md)
+	if err != nil && err != ErrNoCredentials {
+		// the reason is not sent, it helps guessing the credentials
+		return ctx, authError(codes.Unauthenticated, 401, "invalid credentials")
+	}
+	if principal == nil {
+		if rule.Public {
+			return ctx, nil
+		}
+		return ctx, authError(codes.Unauthenticated, 401, "credentials are required")
+	}
+	if !principal.HasScopes(rule.Scopes) {
+		return ctx, authError(codes.PermissionDenied, 403, "the caller lacks the required scopes")
+	}
+	return ContextWithPrincipal(ctx, principal), nil
+}
+
+// UnaryServerInterceptor authenticates the unary calls.
+func (a *Authenticator) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.Authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor authenticates the streaming calls.
+func (a *Authenticator) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.Authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	wrapped := grpc_middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+	return handler(srv, wrapped)
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestJWTVerifierRequiresExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: key.Public(), KeyID: "k1", Algorithm: string(jose.RS256), Use: "sig"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewJWTVerifier(jwksFile, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.Claims
+		ok     bool
+	}{
+		{"with exp", jwt.Claims{Subject: "alice", Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}, true},
+		{"expired", jwt.Claims{Subject: "alice", Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))}, false},
+		{"without exp", jwt.Claims{Subject: "alice"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := jwt.Signed(signer).Claims(tt.claims).CompactSerialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := v.Verify(metadata.Pairs("authorization", "Bearer "+raw))
+			if tt.ok && (err != nil || p.Subject != "alice") {
+				t.Errorf("Verify = %v, %v, want alice", p, err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("Verify accepted the token")
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultBodyCaptureMaxSize is the body size cap used when
+// BodyCaptureConfig.MaxSize is zero.
+const DefaultBodyCaptureMaxSize = 4096
+
+const (
+	// redactedValue replaces the values of the redacted fields
+	redactedValue = "[REDACTED]"
+	// omittedBody replaces the bodies which can't be redacted or aren't text
+	omittedBody = "[omitted]"
+	// truncatedMark ends the bodies cut at the size cap
+	truncatedMark = "...[truncated]"
+)
+
+// BodyCaptureConfig turns on the payload capture of the access logs. It is
+// meant for debugging, the captured requests are the ones matching Routes or
+// Methods plus a SampleRate share of all the others.
+type BodyCaptureConfig struct {
+	// Routes are the google.api.http path templates of the captured
+	// gateway requests, e.g. "/v1/echo/{name}"
+	Routes []string `yaml:"routes" json:"routes"`
+	// Methods are the full gRPC methods of the captured calls,
+	// e.g. "/echo.EchoService/PostEcho", or "/echo.EchoService/*"
+	Methods []string `yaml:"methods" json:"methods"`
+	// SampleRate is the share of the other requests captured, from 0 to 1
+	SampleRate float64 `yaml:"sample_rate" json:"sample_rate"`
+	// MaxSize is the most bytes captured of each body
+	MaxSize int `yaml:"max_size" json:"max_size"`
+	// Redact are the paths of the JSON fields logged as "[REDACTED]", e.g.
+	// "em_id.id". The names match ignoring the case and the underscores, so
+	// the path covers the proto and the JSON names, "*" matches any field.
+	Redact []string `yaml:"redact" json:"redact"`
+}
+
+// LoadBodyCaptureConfig reads the body capture config from a YAML or JSON file.
+func LoadBodyCaptureConfig(file string) (BodyCaptureConfig, error) {
+	var cfg BodyCaptureConfig
+	if err := loadConfig(file, &cfg); err != nil {
+		return cfg, fmt.Errorf("load body capture config %s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+// BodyCapture decides which requests get their payloads logged and renders
+// the payloads redacted. The nil *BodyCapture captures nothing.
+type BodyCapture struct {
+	cfg     BodyCaptureConfig
+	routes  []pathTemplate
+	methods map[string]struct{}
+	redact  [][]string
+}
+
+func NewBodyCapture(cfg BodyCaptureConfig) (*BodyCapture, error) {
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("body capture sample rate %v is out of [0, 1]", cfg.SampleRate)
+	}
+	if cfg.MaxSize < 0 {
+		return nil, fmt.Errorf("body capture max size %d is negative", cfg.MaxSize)
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultBodyCaptureMaxSize
+	}
+	c := &BodyCapture{cfg: cfg, methods: make(map[string]struct{})}
+	for _, route := range cfg.Routes {
+		t, err := parsePathTemplate(route)
+		if err != nil {
+			return nil, fmt.Errorf("body capture route: %w", err)
+		}
+		c.routes = append(c.routes, t)
+	}
+	for _, m := range cfg.Methods {
+		c.methods[m] = struct{}{}
+	}
+	for _, p := range cfg.Redact {
+		fields := strings.Split(p, ".")
+		for i := range fields {
+			fields[i] = normalizeFieldName(fields[i])
+		}
+		c.redact = append(c.redact, fields)
+	}
+	return c, nil
+}
+
+func (c *BodyCapture) sampled() bool {
+	return c.cfg.SampleRate > 0 && rand.Float64() < c.cfg.SampleRate
+}
+
+func (c *BodyCapture) captureRequest(r *http.Request) bool {
+	if c == nil {
+		return false
+	}
+	for _, route := range c.routes {
+		if route.match(r.URL.Path) {
+			return true
+		}
+	}
+	return c.sampled()
+}
+
+// CaptureMethod reports whether the payloads of the gRPC call are logged.
+func (c *BodyCapture) CaptureMethod(fullMethod string) bool {
+	if c == nil {
+		return false
+	}
+	if _, ok := c.methods[fullMethod]; ok {
+		return true
+	}
+	if _, ok := c.methods[path.Dir(fullMethod)+"/*"]; ok {
+		return true
+	}
+	return c.sampled()
+}
+
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", ""))
+}
+
+// redactValue replaces the fields of the path in v, the arrays are crossed
+// without taking a path segment.
+func redactValue(v interface{}, fields []string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if fields[0] != "*" && normalizeFieldName(k) != fields[0] {
+				continue
+			}
+			if len(fields) == 1 {
+				t[k] = redactedValue
+			} else {
+				redactValue(child, fields[1:])
+			}
+		}
+	case []interface{}:
+		for _, e := range t {
+			redactValue(e, fields)
+		}
+	}
+}
+
+// redactJSON redacts a JSON document or a newline delimited stream of them.
+func (c *BodyCapture) redactJSON(data []byte) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var res []string
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+		for _, fields := range c.redact {
+			redactValue(v, fields)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		res = append(res, string(b))
+	}
+	return strings.Join(res, "\n"), true
+}
+
+// render returns the captured body for the log record. With the redaction
+// on, the bodies which aren't JSON are omitted rather than logged as is.
+func (c *BodyCapture) render(b *captureBuffer) string {
+	data := b.buf.Bytes()
+	switch {
+	case len(data) == 0:
+		return ""
+	case !utf8.Valid(data):
+		return omittedBody
+	case len(c.redact) == 0:
+		if b.truncated {
+			return string(data) + truncatedMark
+		}
+		return string(data)
+	}
+	if b.truncated {
+		// the cut document can't be parsed, the complete lines of a stream can
+		i := bytes.LastIndexByte(data, '\n')
+		if i < 0 {
+			return omittedBody
+		}
+		data = data[:i+1]
+	}
+	res, ok := c.redactJSON(data)
+	if !ok {
+		return omittedBody
+	}
+	if b.truncated {
+		res += truncatedMark
+	}
+	return res
+}
+
+// captureBuffer keeps the first max bytes written to it.
+type captureBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *captureBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// PayloadCapture collects the protojson of the messages of a gRPC call,
+// one message per line.
+type PayloadCapture struct {
+	c   *BodyCapture
+	buf captureBuffer
+}
+
+func (c *BodyCapture) NewPayloadCapture() *PayloadCapture {
+	return &PayloadCapture{c: c, buf: captureBuffer{max: c.cfg.MaxSize}}
+}
+
+func (p *PayloadCapture) Add(m interface{}) {
+	msg, ok := m.(proto.Message)
+	if !ok || !msg.ProtoReflect().IsValid() {
+		return
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if p.buf.buf.Len() > 0 {
+		_, _ = p.buf.Write([]byte{'\n'})
+	}
+	_, _ = p.buf.Write(b)
+}
+
+// String returns the redacted payloads.
+func (p *PayloadCapture) String() string {
+	return p.c.render(&p.buf)
+}
+
+// capturedBodies carries the bodies from the capture middleware to the
+// access log record.
+type capturedBodies struct {
+	captured bool
+	request  string
+	response string
+}
+
+type capturedBodiesKey struct{}
+
+func withCapturedBodies(ctx context.Context) (context.Context, *capturedBodies) {
+	bodies := &capturedBodies{}
+	return context.WithValue(ctx, capturedBodiesKey{}, bodies), bodies
+}
+
+type bodyCaptureHandler struct {
+	next    http.Handler
+	capture *BodyCapture
+}
+
+func (h *bodyCaptureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bodies, ok := r.Context().Value(capturedBodiesKey{}).(*capturedBodies)
+	// the WebSocket messages are not a body
+	if !ok || r.Header.Get("Upgrade") != "" || !h.capture.captureRequest(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	req := &captureBuffer{max: h.capture.cfg.MaxSize}
+	if r.Body != nil {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(r.Body, req), r.Body}
+	}
+	res, rw := wrapResponseWriter(w)
+	res.capture = &captureBuffer{max: h.capture.cfg.MaxSize}
+	h.next.ServeHTTP(rw, r)
+
+	bodies.captured = true
+	bodies.request = h.capture.render(req)
+	bodies.response = h.capture.render(res.capture)
+}
+
+// WithBodyCaptureMiddleware captures the request and response bodies of the
+// requests picked by capture into the access log record of
+// WithLoggingMiddleware as request_body and response_body. Put it inside
+// WithCompressionMiddleware to get the bodies decoded.
+func WithBodyCaptureMiddleware(h http.Handler, capture *BodyCapture) http.Handler {
+	return &bodyCaptureHandler{next: h, capture: capture}
+}
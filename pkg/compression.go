@@ -0,0 +1,397 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CompressionConfig configures the response compression of the gateway.
+type CompressionConfig struct {
+	// MinSize is the smallest response compressed, the streamed responses
+	// are compressed from the first flush regardless of the size
+	MinSize int
+	// ContentTypes are the compressed media types, entries ending with "/"
+	// match by prefix, e.g. "text/"
+	ContentTypes []string
+	// MaxDecodedSize is the most bytes of a decoded request body,
+	// DefaultMaxDecodedSize unless positive
+	MaxDecodedSize int64
+}
+
+// DefaultMaxDecodedSize is the decoded request body limit used when
+// CompressionConfig.MaxDecodedSize is zero, the default gRPC message limit.
+const DefaultMaxDecodedSize = 4 << 20
+
+// DefaultCompressionContentTypes are the media types compressed when
+// CompressionConfig.ContentTypes is empty.
+var DefaultCompressionContentTypes = []string{"application/json", NDJSONContentType, ProblemContentType, "text/"}
+
+// encodings are the supported content codings, preferred first.
+var encodings = []string{"br", "zstd", "gzip"}
+
+// encoder is a compressing writer of a content coding.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	"br": {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}},
+	"zstd": {New: func() interface{} {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// negotiateEncoding picks the content coding by the Accept-Encoding header,
+// it is empty when the response goes out as is.
+func negotiateEncoding(r *http.Request) string {
+	qs := make(map[string]float64)
+	for _, accept := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(accept, ",") {
+			fields := strings.Split(part, ";")
+			name := strings.ToLower(strings.TrimSpace(fields[0]))
+			q := 1.0
+			for _, param := range fields[1:] {
+				if kv := strings.SplitN(strings.TrimSpace(param), "=", 2); len(kv) == 2 && kv[0] == "q" {
+					if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+						q = v
+					}
+				}
+			}
+			qs[name] = q
+		}
+	}
+	best, bestQ := "", 0.0
+	for _, enc := range encodings {
+		q, ok := qs[enc]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressWriter compresses the response once it is known to be worth it:
+// the body reached MinSize or the handler flushed.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *CompressionConfig
+	encoding string
+
+	status  int
+	decided bool
+	enc     encoder
+	buf     bytes.Buffer
+	// stats is the writer of the logging middleware when it is the next one
+	stats *responseWriter
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) compressible() bool {
+	switch {
+	case w.status == http.StatusNoContent || w.status == http.StatusNotModified || w.status < http.StatusOK:
+		return false
+	case w.Header().Get("Content-Encoding") != "":
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, t := range w.cfg.ContentTypes {
+		if t == mediaType || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decide starts the response, compressed or not, and writes the buffer.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if compress && w.compressible() {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+		w.enc = encoderPools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+		if w.stats != nil {
+			w.stats.compressed = true
+		}
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.writeOut(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) writeOut(b []byte) (int, error) {
+	if w.enc == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.stats != nil {
+		w.stats.uncompressedSize += len(b)
+	}
+	return w.enc.Write(b)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		return w.writeOut(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() >= w.cfg.MinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// cwFlusher sends the chunks of the streamed responses as they come.
+type cwFlusher struct{ cw *compressWriter }
+
+func (f cwFlusher) Flush() {
+	w := f.cw
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return
+		}
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// cwHijacker allows the hijack before anything is written only.
+type cwHijacker struct{ cw *compressWriter }
+
+func (h cwHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h.cw.decided {
+		return nil, nil, errors.New("hijack after the response started")
+	}
+	h.cw.decided = true
+	return h.cw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// cwPusher keeps the HTTP/2 server push.
+type cwPusher struct{ cw *compressWriter }
+
+func (p cwPusher) Push(target string, opts *http.PushOptions) error {
+	return p.cw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// wrapCompressWriter returns the writer passed to the next handler, it
+// implements the optional interfaces of the writer of cw among http.Flusher,
+// http.Hijacker and http.Pusher. io.ReaderFrom is left out, the body goes
+// through the encoder.
+func wrapCompressWriter(cw *compressWriter) http.ResponseWriter {
+	const (
+		flusher = 1 << iota
+		hijacker
+		pusher
+	)
+	var mask int
+	if _, ok := cw.ResponseWriter.(http.Flusher); ok {
+		mask |= flusher
+	}
+	if _, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		mask |= hijacker
+	}
+	if _, ok := cw.ResponseWriter.(http.Pusher); ok {
+		mask |= pusher
+	}
+
+	f, h, p := cwFlusher{cw}, cwHijacker{cw}, cwPusher{cw}
+	switch mask {
+	case flusher:
+		return struct {
+			*compressWriter
+			http.Flusher
+		}{cw, f}
+	case hijacker:
+		return struct {
+			*compressWriter
+			http.Hijacker
+		}{cw, h}
+	case flusher | hijacker:
+		return struct {
+			*compressWriter
+			http.Flusher
+			http.Hijacker
+		}{cw, f, h}
+	case pusher:
+		return struct {
+			*compressWriter
+			http.Pusher
+		}{cw, p}
+	case flusher | pusher:
+		return struct {
+			*compressWriter
+			http.Flusher
+			http.Pusher
+		}{cw, f, p}
+	case hijacker | pusher:
+		return struct {
+			*compressWriter
+			http.Hijacker
+			http.Pusher
+		}{cw, h, p}
+	case flusher | hijacker | pusher:
+		return struct {
+			*compressWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{cw, f, h, p}
+	}
+	return cw
+}
+
+// close ends the response, the short responses are sent uncompressed.
+func (w *compressWriter) close() error {
+	if !w.decided {
+		if w.status == 0 && w.buf.Len() == 0 {
+			// nothing was written, the server replies 200 itself
+			return nil
+		}
+		if err := w.decide(w.buf.Len() >= w.cfg.MinSize); err != nil {
+			return err
+		}
+	}
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	encoderPools[w.encoding].Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// decompressBody replaces the body encoded by the Content-Encoding header
+// with the decoded one, reading more than maxSize decoded bytes fails.
+func decompressBody(w http.ResponseWriter, r *http.Request, maxSize int64) error {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || r.Body == nil {
+		return nil
+	}
+	var body io.ReadCloser
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return err
+		}
+		body = zr
+	case "br":
+		body = io.NopCloser(brotli.NewReader(r.Body))
+	case "zstd":
+		zr, err := zstd.NewReader(r.Body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		body = zr.IOReadCloser()
+	default:
+		return errors.New("unsupported content encoding " + encoding)
+	}
+	// a few compressed bytes can decode to gigabytes
+	r.Body = http.MaxBytesReader(w, struct {
+		io.Reader
+		io.Closer
+	}{body, multiCloser{body, r.Body}}, maxSize)
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+type multiCloser []io.Closer
+
+func (c multiCloser) Close() error {
+	var res error
+	for _, cl := range c {
+		if err := cl.Close(); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+type compressionHandler struct {
+	next         http.Handler
+	cfg          CompressionConfig
+	errorHandler func(http.ResponseWriter, *http.Request, error)
+}
+
+func (h *compressionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := decompressBody(w, r, h.cfg.MaxDecodedSize); err != nil {
+		h.errorHandler(w, r, status.Error(codes.InvalidArgument, "can't decode the request body: "+err.Error()))
+		return
+	}
+	encoding := negotiateEncoding(r)
+	// WebSockets and HEAD requests have no body to compress
+	if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	cw := &compressWriter{ResponseWriter: w, cfg: &h.cfg, encoding: encoding}
+	if s, ok := w.(interface{ stats() *responseWriter }); ok {
+		cw.stats = s.stats()
+	}
+	h.next.ServeHTTP(wrapCompressWriter(cw), r)
+	_ = cw.close()
+}
+
+// WithCompressionMiddleware compresses the responses with br, zstd or gzip
+// negotiated by Accept-Encoding and decodes the request bodies sent with
+// Content-Encoding. The undecodable bodies are InvalidArgument errors
+// written by errorHandler, the ones decoding to more than cfg.MaxDecodedSize
+// fail the reads of the handler, an InvalidArgument error of the gateway. Put it right inside WithLoggingMiddleware to
+// get both the compressed and the uncompressed bytes_out logged.
+func WithCompressionMiddleware(h http.Handler, cfg CompressionConfig, errorHandler func(http.ResponseWriter, *http.Request, error)) http.Handler {
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultCompressionContentTypes
+	}
+	if cfg.MaxDecodedSize <= 0 {
+		cfg.MaxDecodedSize = DefaultMaxDecodedSize
+	}
+	return &compressionHandler{next: h, cfg: cfg, errorHandler: errorHandler}
+}
@@ -0,0 +1,14 @@
+package pkg
+
+import "testing"
+
+func TestWrapCompressWriterInterfaces(t *testing.T) {
+	for mask := 0; mask < 16; mask++ {
+		w, _ := fakeWriter(mask)
+		wrapped := wrapCompressWriter(&compressWriter{ResponseWriter: w})
+		// the body goes through the encoder, ReadFrom would skip it
+		if got, want := interfaceMask(wrapped), mask&^maskReaderFrom; got != want {
+			t.Errorf("wrapped %04b writer implements %04b, want %04b", mask, got, want)
+		}
+	}
+}
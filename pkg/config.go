@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loadConfig decodes the YAML or JSON file into v, the format is picked by
+// the file extension. The unknown keys are errors in both formats, so a
+// misspelled option fails the startup instead of being ignored.
+func loadConfig(file string, v interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(filepath.Ext(file), ".json") {
+		return yaml.UnmarshalStrict(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,35 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+		ok   bool
+	}{
+		{"ratelimit.yaml", "key: ip\nmax_header_keys: 10\n", true},
+		{"ratelimit.yaml", "key: ip\nmax_headers_keys: 10\n", false},
+		{"ratelimit.json", `{"key": "ip", "max_header_keys": 10}`, true},
+		{"ratelimit.json", `{"key": "ip", "max_headers_keys": 10}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			if err := ioutil.WriteFile(file, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadRateLimitConfig(file)
+			if tt.ok && (err != nil || cfg.Key != "ip" || cfg.MaxHeaderKeys != 10) {
+				t.Errorf("LoadRateLimitConfig = %+v, %v", cfg, err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("LoadRateLimitConfig accepted the unknown key")
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsExposedHeaders are set by the gateway itself and are exposed
+// in addition to the outgoing header allow-list.
+var corsExposedHeaders = []string{"retry-after", "x-ratelimit-limit", "x-ratelimit-remaining", "x-ratelimit-reset"}
+
+// CORSConfig configures the cross-origin requests of browser clients.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins, e.g. "https://app.example.com",
+	// wildcards, e.g. "https://*.example.com", or "*" for any origin
+	AllowedOrigins []string
+	// AllowedMethods default to GET, POST, PUT, PATCH and DELETE
+	AllowedMethods []string
+	// AllowedHeaders are the request headers the clients may send, by
+	// default the headers passing the incoming header rules
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the clients,
+	// by default the outgoing header allow-list
+	ExposedHeaders []string
+	// AllowCredentials lets the clients send cookies and authorization,
+	// it can't be combined with the "*" origin
+	AllowCredentials bool
+	// MaxAge is how long the browsers cache the preflight response
+	MaxAge time.Duration
+}
+
+type corsHandler struct {
+	next      http.Handler
+	cfg       CORSConfig
+	headers   *HeaderMatchers
+	methods   map[string]struct{}
+	allowed   map[string]struct{}
+	anyOrigin bool
+}
+
+func (h *corsHandler) originAllowed(origin string) bool {
+	if h.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range h.cfg.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		if i := strings.IndexByte(o, '*'); i >= 0 {
+			// the wildcard needs at least one character
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+			continue
+		}
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *corsHandler) headerAllowed(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if h.allowed != nil {
+		_, ok := h.allowed[name]
+		return ok
+	}
+	// the headers the gateway forwards, permanent ones like Content-Type included
+	if name == "authorization" {
+		return true
+	}
+	_, ok := h.headers.Incoming(name)
+	return ok
+}
+
+func (h *corsHandler) exposedHeaders() string {
+	if len(h.cfg.ExposedHeaders) > 0 {
+		return strings.Join(h.cfg.ExposedHeaders, ", ")
+	}
+	return strings.Join(append(h.headers.OutgoingNames(), corsExposedHeaders...), ", ")
+}
+
+// setAllowOrigin allows the origin of the request and its credentials.
+func (h *corsHandler) setAllowOrigin(w http.ResponseWriter, origin string) {
+	allowOrigin := origin
+	if h.anyOrigin {
+		allowOrigin = "*"
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+	if h.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if !h.originAllowed(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// the browser blocks the response without the CORS headers
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	if !preflight {
+		h.setAllowOrigin(w, origin)
+		w.Header().Set("Access-Control-Expose-Headers", h.exposedHeaders())
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if _, ok := h.methods[method]; !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var requested []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if !h.headerAllowed(name) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			requested = append(requested, name)
+		}
+	}
+	// the rejected preflights get no CORS headers
+	h.setAllowOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", method)
+	if len(requested) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if h.cfg.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(h.cfg.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WithCORSMiddleware answers the CORS preflight requests and adds the CORS
+// headers to the responses of the allowed origins. The allowed and exposed
+// headers follow the header rules of headers unless cfg lists them.
+// The "*" origin with AllowCredentials is an error, it would let any site
+// make the authenticated requests of the users.
+func WithCORSMiddleware(h http.Handler, cfg CORSConfig, headers *HeaderMatchers) (http.Handler, error) {
+	res := &corsHandler{next: h, cfg: cfg, headers: headers, methods: make(map[string]struct{})}
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	for _, m := range methods {
+		res.methods[strings.ToUpper(m)] = struct{}{}
+	}
+	if len(cfg.AllowedHeaders) > 0 {
+		res.allowed = make(map[string]struct{})
+		for _, name := range cfg.AllowedHeaders {
+			res.allowed[strings.ToLower(name)] = struct{}{}
+		}
+	}
+	for _, o := range cfg.AllowedOrigins {
+		if strings.TrimSpace(o) == "*" {
+			res.anyOrigin = true
+		}
+	}
+	if res.anyOrigin && cfg.AllowCredentials {
+		return nil, errors.New(`CORS origin "*" can't allow credentials, list the origins`)
+	}
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	h, err := WithCORSMiddleware(http.NotFoundHandler(), CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodPost},
+		AllowedHeaders:   []string{"x-trace-id"},
+		AllowCredentials: true,
+	}, NewHeaderMatchers(HeadersConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		want    int
+	}{
+		{"allowed", "https://app.example.com", http.MethodPost, "X-Trace-Id", http.StatusNoContent},
+		{"origin", "https://evil.example.com", http.MethodPost, "", http.StatusForbidden},
+		{"method", "https://app.example.com", http.MethodDelete, "", http.StatusForbidden},
+		{"header", "https://app.example.com", http.MethodPost, "X-Trace-Id, Cookie", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/v1/echo/abc", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			// only the validated preflights allow the origin and its credentials
+			wantOrigin, wantCredentials := "", ""
+			if tt.want == http.StatusNoContent {
+				wantOrigin, wantCredentials = tt.origin, "true"
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+		})
+	}
+}
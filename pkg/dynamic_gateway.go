@@ -0,0 +1,337 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// reflectionClient asks the server reflection service of a backend, one
+// request at a time.
+type reflectionClient struct {
+	stream rpb.ServerReflection_ServerReflectionInfoClient
+	// files are the descriptors received so far by name, the server sends
+	// every file once per stream
+	files map[string]*descriptorpb.FileDescriptorProto
+}
+
+func (c *reflectionClient) ask(req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	if err := c.stream.Send(req); err != nil {
+		return nil, err
+	}
+	res, err := c.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := res.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.ErrorCode), e.ErrorMessage)
+	}
+	for _, b := range res.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(b, fd); err != nil {
+			return nil, fmt.Errorf("parse file descriptor: %w", err)
+		}
+		c.files[fd.GetName()] = fd
+	}
+	return res, nil
+}
+
+// register adds the file and its dependencies to registry, the files not
+// received yet are asked by name.
+func (c *reflectionClient) register(registry *protoregistry.Files, name string) error {
+	if _, err := registry.FindFileByPath(name); err == nil {
+		return nil
+	}
+	fd, ok := c.files[name]
+	if !ok {
+		_, err := c.ask(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+		})
+		if err != nil {
+			return fmt.Errorf("get file %s: %w", name, err)
+		}
+		if fd, ok = c.files[name]; !ok {
+			return fmt.Errorf("backend sent no file %s", name)
+		}
+	}
+	for _, dep := range fd.GetDependency() {
+		if err := c.register(registry, dep); err != nil {
+			return err
+		}
+	}
+	f, err := protodesc.NewFile(fd, registry)
+	if err != nil {
+		return fmt.Errorf("build file %s: %w", name, err)
+	}
+	return registry.RegisterFile(f)
+}
+
+// DiscoverServices lists the services of the backend with the gRPC server
+// reflection API (v1alpha) and returns their descriptors, the method options
+// keep the google.api.http annotations. The reflection service itself is left out.
+func DiscoverServices(ctx context.Context, conn grpc.ClientConnInterface) ([]protoreflect.ServiceDescriptor, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &reflectionClient{stream: stream, files: make(map[string]*descriptorpb.FileDescriptorProto)}
+
+	res, err := c.ask(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+	var names []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		if strings.HasPrefix(s.GetName(), "grpc.reflection.") {
+			continue
+		}
+		names = append(names, s.GetName())
+		_, err := c.ask(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: s.GetName()},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get service %s: %w", s.GetName(), err)
+		}
+	}
+
+	registry := new(protoregistry.Files)
+	files := make([]string, 0, len(c.files))
+	for name := range c.files {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		// register asks for the dependencies not received yet
+		if err := c.register(registry, name); err != nil {
+			return nil, err
+		}
+	}
+	_ = stream.CloseSend()
+
+	var services []protoreflect.ServiceDescriptor
+	for _, name := range names {
+		desc, err := registry.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("find service %s: %w", name, err)
+		}
+		sd, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a service", name)
+		}
+		services = append(services, sd)
+	}
+	return services, nil
+}
+
+// pathParamRe matches the variables of a path template, e.g. "{echo.name}"
+// or "{name=shelves/*}".
+var pathParamRe = regexp.MustCompile(`{([^}=]+)`)
+
+// dynamicRoute proxies the requests of a google.api.http binding to the
+// method, the messages are dynamicpb messages built from the descriptors.
+type dynamicRoute struct {
+	mux        *runtime.ServeMux
+	conn       grpc.ClientConnInterface
+	method     protoreflect.MethodDescriptor
+	fullMethod string
+	path       string
+	// body is the request field set from the body, "*" is the whole request
+	body         string
+	responseBody protoreflect.FieldDescriptor
+	// filter keeps the path and body fields out of the query parameters
+	filter *utilities.DoubleArray
+}
+
+func newDynamicRoute(mux *runtime.ServeMux, conn grpc.ClientConnInterface, md protoreflect.MethodDescriptor, rule *annotations.HttpRule, path string) (*dynamicRoute, error) {
+	r := &dynamicRoute{
+		mux:        mux,
+		conn:       conn,
+		method:     md,
+		fullMethod: fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name()),
+		path:       path,
+		body:       rule.GetBody(),
+	}
+	var seqs [][]string
+	for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+		seqs = append(seqs, strings.Split(m[1], "."))
+	}
+	if r.body != "" && r.body != "*" {
+		fd := md.Input().Fields().ByName(protoreflect.Name(r.body))
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("body %q of %s is not a message field", r.body, r.fullMethod)
+		}
+		seqs = append(seqs, []string{r.body})
+	}
+	r.filter = utilities.NewDoubleArray(seqs)
+	if name := rule.GetResponseBody(); name != "" {
+		fd := md.Output().Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("response body %q of %s is not a message field", name, r.fullMethod)
+		}
+		r.responseBody = fd
+	}
+	return r, nil
+}
+
+func (r *dynamicRoute) request(req *http.Request, marshaler runtime.Marshaler, pathParams map[string]string) (proto.Message, error) {
+	msg := dynamicpb.NewMessage(r.method.Input())
+	switch r.body {
+	case "":
+	case "*":
+		if err := marshaler.NewDecoder(req.Body).Decode(msg); err != nil && err != io.EOF {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	default:
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(r.body))
+		if err := marshaler.NewDecoder(req.Body).Decode(msg.Mutable(fd).Message().Interface()); err != nil && err != io.EOF {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+	for name, value := range pathParams {
+		if err := runtime.PopulateFieldFromPath(msg, name, value); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", name, err)
+		}
+	}
+	if r.body != "*" {
+		if err := req.ParseForm(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		if err := runtime.PopulateQueryParameters(msg, req.Form, r.filter); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+	return msg, nil
+}
+
+// dynamicResponseBody sends the response_body field of the response only.
+type dynamicResponseBody struct {
+	proto.Message
+	body proto.Message
+}
+
+func (r dynamicResponseBody) XXX_ResponseBody() interface{} {
+	return r.body
+}
+
+func (r *dynamicRoute) response(msg *dynamicpb.Message) proto.Message {
+	if r.responseBody == nil {
+		return msg
+	}
+	return dynamicResponseBody{Message: msg, body: msg.Get(r.responseBody).Message().Interface()}
+}
+
+func (r *dynamicRoute) serveHTTP(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	inbound, outbound := runtime.MarshalerForRequest(r.mux, req)
+	ctx, err := runtime.AnnotateContext(ctx, r.mux, req, r.fullMethod)
+	if err != nil {
+		runtime.HTTPError(ctx, r.mux, outbound, w, req, err)
+		return
+	}
+	// RouteAnnotator finds the generated routes only, this one is known here
+	if route, ok := RouteInfoFromContext(req.Context()); ok {
+		route.Pattern = r.path
+	}
+	in, err := r.request(req, inbound, pathParams)
+	if err != nil {
+		runtime.HTTPError(ctx, r.mux, outbound, w, req, err)
+		return
+	}
+
+	var md runtime.ServerMetadata
+	if !r.method.IsStreamingServer() {
+		out := dynamicpb.NewMessage(r.method.Output())
+		err := r.conn.Invoke(ctx, r.fullMethod, in, out, grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, r.mux, outbound, w, req, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, r.mux, outbound, w, req, r.response(out), r.mux.GetForwardResponseOptions()...)
+		return
+	}
+
+	stream, err := r.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, r.fullMethod)
+	if err == nil {
+		err = stream.SendMsg(in)
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	if err == nil {
+		md.HeaderMD, err = stream.Header()
+	}
+	ctx = runtime.NewServerMetadataContext(ctx, md)
+	if err != nil {
+		runtime.HTTPError(ctx, r.mux, outbound, w, req, err)
+		return
+	}
+	runtime.ForwardResponseStream(ctx, r.mux, outbound, w, req, func() (proto.Message, error) {
+		out := dynamicpb.NewMessage(r.method.Output())
+		if err := stream.RecvMsg(out); err != nil {
+			return nil, err
+		}
+		return r.response(out), nil
+	}, r.mux.GetForwardResponseOptions()...)
+}
+
+// RegisterDynamicHandlers registers the google.api.http bindings of the
+// services on mux, the requests are proxied to conn. It is the runtime
+// counterpart of the generated RegisterXxxHandler functions for the
+// services found by DiscoverServices. The client and bidi streaming
+// methods are skipped, they have no REST mapping.
+func RegisterDynamicHandlers(mux *runtime.ServeMux, conn grpc.ClientConnInterface, services []protoreflect.ServiceDescriptor, logger Logger) error {
+	for _, sd := range services {
+		methods := sd.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			md := methods.Get(i)
+			rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if !ok || rule == nil {
+				continue
+			}
+			fullMethod := fmt.Sprintf("/%s/%s", sd.FullName(), md.Name())
+			if md.IsStreamingClient() {
+				logger.Info("dynamic route skipped", "rpc_method", fullMethod, "reason", "client streaming")
+				continue
+			}
+			for _, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+				method, path := httpRuleBinding(binding)
+				if method == "" || path == "" {
+					continue
+				}
+				route, err := newDynamicRoute(mux, conn, md, binding, path)
+				if err != nil {
+					return err
+				}
+				if err := mux.HandlePath(method, path, route.serveHTTP); err != nil {
+					return fmt.Errorf("register %s %s of %s: %w", method, path, fullMethod, err)
+				}
+				logger.Info("dynamic route", "method", method, "pattern", path, "rpc_method", fullMethod)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// HeaderRules describes which headers pass the gateway in one direction.
+// Allow and Deny entries are exact names or prefixes ending with "*",
+// e.g. "x-tenant-*". Rename maps a source name to the forwarded name and
+// allows the header implicitly. Deny wins over Allow and Rename.
+type HeaderRules struct {
+	Allow  []string          `yaml:"allow" json:"allow"`
+	Deny   []string          `yaml:"deny" json:"deny"`
+	Rename map[string]string `yaml:"rename" json:"rename"`
+}
+
+// HeadersConfig is the header allow-list configuration.
+// Incoming rules map HTTP request headers to gRPC metadata,
+// outgoing rules map gRPC response metadata to HTTP headers.
+type HeadersConfig struct {
+	Incoming HeaderRules `yaml:"incoming" json:"incoming"`
+	Outgoing HeaderRules `yaml:"outgoing" json:"outgoing"`
+}
+
+// LoadHeadersConfig reads the headers config from a YAML or JSON file.
+func LoadHeadersConfig(path string) (HeadersConfig, error) {
+	var cfg HeadersConfig
+	if err := loadConfig(path, &cfg); err != nil {
+		return cfg, fmt.Errorf("load headers config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+type headerRules struct {
+	allow       map[string]struct{}
+	allowPrefix []string
+	deny        map[string]struct{}
+	denyPrefix  []string
+	rename      map[string]string
+}
+
+func compileHeaderRules(r HeaderRules) *headerRules {
+	res := &headerRules{
+		allow:  make(map[string]struct{}),
+		deny:   make(map[string]struct{}),
+		rename: make(map[string]string),
+	}
+	for _, h := range r.Allow {
+		h = strings.ToLower(h)
+		if strings.HasSuffix(h, "*") {
+			res.allowPrefix = append(res.allowPrefix, strings.TrimSuffix(h, "*"))
+			continue
+		}
+		res.allow[h] = struct{}{}
+	}
+	for _, h := range r.Deny {
+		h = strings.ToLower(h)
+		if strings.HasSuffix(h, "*") {
+			res.denyPrefix = append(res.denyPrefix, strings.TrimSuffix(h, "*"))
+			continue
+		}
+		res.deny[h] = struct{}{}
+	}
+	for from, to := range r.Rename {
+		res.rename[strings.ToLower(from)] = strings.ToLower(to)
+	}
+	return res
+}
+
+// match returns the forwarded name, whether the header passes and whether
+// the rules decided about the header at all.
+func (r *headerRules) match(key string) (string, bool, bool) {
+	if _, ok := r.deny[key]; ok {
+		return "", false, true
+	}
+	for _, p := range r.denyPrefix {
+		if strings.HasPrefix(key, p) {
+			return "", false, true
+		}
+	}
+	if to, ok := r.rename[key]; ok {
+		return to, true, true
+	}
+	if _, ok := r.allow[key]; ok {
+		return key, true, true
+	}
+	for _, p := range r.allowPrefix {
+		if strings.HasPrefix(key, p) {
+			return key, true, true
+		}
+	}
+	return "", false, false
+}
+
+// names returns the exact forwarded names, the prefix rules can't be listed.
+func (r *headerRules) names() []string {
+	var res []string
+	add := func(key, name string) {
+		if _, ok := r.deny[key]; ok {
+			return
+		}
+		for _, p := range r.denyPrefix {
+			if strings.HasPrefix(key, p) {
+				return
+			}
+		}
+		res = append(res, name)
+	}
+	for h := range r.allow {
+		if _, ok := r.rename[h]; !ok {
+			add(h, h)
+		}
+	}
+	for from, to := range r.rename {
+		add(from, to)
+	}
+	sort.Strings(res)
+	return res
+}
+
+type compiledHeaders struct {
+	incoming *headerRules
+	outgoing *headerRules
+}
+
+// HeaderMatchers are the gateway header matchers built from HeadersConfig.
+// The config may be replaced at any time, requests in flight keep the rules
+// they started with.
+type HeaderMatchers struct {
+	rules atomic.Value // *compiledHeaders
+}
+
+func NewHeaderMatchers(cfg HeadersConfig) *HeaderMatchers {
+	m := &HeaderMatchers{}
+	m.Set(cfg)
+	return m
+}
+
+// Set replaces the config.
+func (m *HeaderMatchers) Set(cfg HeadersConfig) {
+	m.rules.Store(&compiledHeaders{
+		incoming: compileHeaderRules(cfg.Incoming),
+		outgoing: compileHeaderRules(cfg.Outgoing),
+	})
+}
+
+// Load replaces the config with the one read from path.
+// The current config is kept when the file can't be read.
+func (m *HeaderMatchers) Load(path string) error {
+	cfg, err := LoadHeadersConfig(path)
+	if err != nil {
+		return err
+	}
+	m.Set(cfg)
+	return nil
+}
+
+func (m *HeaderMatchers) load() *compiledHeaders {
+	return m.rules.Load().(*compiledHeaders)
+}
+
+// Incoming is a runtime.HeaderMatcherFunc for HTTP request headers.
+// Note: grpc-gateway always forwards Authorization as "authorization"
+// in addition to what the matcher returns.
+// ClientCertSubjectKey is never forwarded, it is set by ClientCertAnnotator only.
+func (m *HeaderMatchers) Incoming(key string) (string, bool) {
+	lower := strings.ToLower(key)
+	h, ok, decided := m.load().incoming.match(lower)
+	if !decided {
+		h, ok = runtime.DefaultHeaderMatcher(key)
+	}
+	if ok && strings.EqualFold(h, ClientCertSubjectKey) {
+		return "", false
+	}
+	return h, ok
+}
+
+// Outgoing is a runtime.HeaderMatcherFunc for gRPC response metadata.
+func (m *HeaderMatchers) Outgoing(key string) (string, bool) {
+	if key == "grpcgateway-content-type" {
+		return "", false
+	}
+	lower := strings.ToLower(key)
+	if h, ok, decided := m.load().outgoing.match(lower); decided {
+		return h, ok
+	}
+	return runtime.DefaultHeaderMatcher(lower)
+}
+
+// OutgoingNames returns the HTTP response headers forwarded by the exact
+// outgoing rules, the prefix rules are not listed.
+func (m *HeaderMatchers) OutgoingNames() []string {
+	return m.load().outgoing.names()
+}
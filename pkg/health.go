@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// readinessTimeout bounds the health check of the backend per /readyz probe.
+const readinessTimeout = time.Second
+
+var errShuttingDown = errors.New("shutting down")
+
+// HealthChecker serves the liveness and readiness probes of the gateway.
+// The readiness follows the grpc.health.v1 status of the backend service,
+// asked over the gateway connection, and turns off for good on shutdown.
+type HealthChecker struct {
+	server  *health.Server
+	conn    *grpc.ClientConn
+	service string
+
+	shuttingDown int32
+}
+
+// NewHealthChecker checks service on the backend behind conn. conn may be nil
+// when the gateway calls the server in-process, server is asked directly then.
+func NewHealthChecker(server *health.Server, conn *grpc.ClientConn, service string) *HealthChecker {
+	return &HealthChecker{server: server, conn: conn, service: service}
+}
+
+// Shutdown marks the gateway not ready and every service of the health server
+// NOT_SERVING, call it before draining the listeners.
+func (c *HealthChecker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+	c.server.Shutdown()
+}
+
+// Ready returns the reason the gateway can't serve requests, nil when it can.
+func (c *HealthChecker) Ready(ctx context.Context) error {
+	if atomic.LoadInt32(&c.shuttingDown) == 1 {
+		return errShuttingDown
+	}
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	req := &healthpb.HealthCheckRequest{Service: c.service}
+	var res *healthpb.HealthCheckResponse
+	var err error
+	if c.conn != nil {
+		if state := c.conn.GetState(); state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return fmt.Errorf("backend connection is %s", state)
+		}
+		// the call connects an idle connection
+		res, err = healthpb.NewHealthClient(c.conn).Check(ctx, req)
+	} else {
+		res, err = c.server.Check(ctx, req)
+	}
+	if err != nil {
+		return fmt.Errorf("backend health check: %w", err)
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("backend is %s", res.Status)
+	}
+	return nil
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func writeHealth(w http.ResponseWriter, err error) {
+	res, code := healthResponse{Status: "ok"}, http.StatusOK
+	if err != nil {
+		res, code = healthResponse{Status: "unavailable", Reason: err.Error()}, http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// LivenessHandler reports the process is up, it doesn't depend on the backend.
+func (c *HealthChecker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeHealth(w, nil)
+	})
+}
+
+// ReadinessHandler replies 503 with the reason when Ready fails.
+func (c *HealthChecker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, c.Ready(r.Context()))
+	})
+}
+
+// WithHealthHandlers serves /healthz and /readyz in front of h, the probes
+// skip the logging and the metrics of the gateway requests.
+func WithHealthHandlers(h http.Handler, c *HealthChecker) http.Handler {
+	liveness, readiness := c.LivenessHandler(), c.ReadinessHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			liveness.ServeHTTP(w, r)
+		case "/readyz":
+			readiness.ServeHTTP(w, r)
+		default:
+			h.ServeHTTP(w, r)
+		}
+	})
+}
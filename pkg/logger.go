@@ -0,0 +1,155 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger is a structured logger in the log/slog style: a message followed by
+// alternating keys and values. Implementations must write every call as a
+// single record.
+type Logger interface {
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NewLogger returns the stdout style logger for the format, "json" or "logfmt".
+func NewLogger(w io.Writer, format string) (Logger, error) {
+	switch format {
+	case "json":
+		return NewJSONLogger(w), nil
+	case "logfmt":
+		return NewLogfmtLogger(w), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+type writerLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	encode func(buf *bytes.Buffer, level, msg string, keyvals []interface{})
+}
+
+// NewJSONLogger writes one JSON object per line.
+func NewJSONLogger(w io.Writer) Logger {
+	return &writerLogger{w: w, encode: encodeJSON}
+}
+
+// NewLogfmtLogger writes one logfmt line per record.
+func NewLogfmtLogger(w io.Writer) Logger {
+	return &writerLogger{w: w, encode: encodeLogfmt}
+}
+
+func (l *writerLogger) Info(msg string, keyvals ...interface{}) {
+	l.log("info", msg, keyvals)
+}
+
+func (l *writerLogger) Error(msg string, keyvals ...interface{}) {
+	l.log("error", msg, keyvals)
+}
+
+func (l *writerLogger) log(level, msg string, keyvals []interface{}) {
+	var buf bytes.Buffer
+	l.encode(&buf, level, msg, keyvals)
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(buf.Bytes())
+}
+
+func encodeJSON(buf *bytes.Buffer, level, msg string, keyvals []interface{}) {
+	buf.WriteString(`{"time":`)
+	writeJSON(buf, time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(buf, level)
+	buf.WriteString(`,"msg":`)
+	writeJSON(buf, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, val := keyval(keyvals, i)
+		buf.WriteByte(',')
+		writeJSON(buf, key)
+		buf.WriteByte(':')
+		writeJSON(buf, val)
+	}
+	buf.WriteByte('}')
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	switch t := v.(type) {
+	case time.Duration:
+		v = t.String()
+	case error:
+		v = t.Error()
+	case fmt.Stringer:
+		v = t.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+func encodeLogfmt(buf *bytes.Buffer, level, msg string, keyvals []interface{}) {
+	buf.WriteString("time=")
+	buf.WriteString(time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(" level=")
+	buf.WriteString(level)
+	buf.WriteString(" msg=")
+	writeLogfmt(buf, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, val := keyval(keyvals, i)
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		writeLogfmt(buf, val)
+	}
+}
+
+func writeLogfmt(buf *bytes.Buffer, v interface{}) {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		s = strconv.Quote(s)
+	}
+	buf.WriteString(s)
+}
+
+// keyval returns the pair at i, a dangling key gets the "!MISSING" value.
+func keyval(keyvals []interface{}, i int) (string, interface{}) {
+	key := fmt.Sprint(keyvals[i])
+	if i+1 >= len(keyvals) {
+		return key, "!MISSING"
+	}
+	return key, keyvals[i+1]
+}
+
+// SugaredLogger is the subset of *zap.SugaredLogger used by the adapter.
+type SugaredLogger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type sugaredLogger struct {
+	l SugaredLogger
+}
+
+// FromSugaredLogger adapts a zap sugared logger, or anything with the same
+// methods, to Logger.
+func FromSugaredLogger(l SugaredLogger) Logger {
+	return &sugaredLogger{l: l}
+}
+
+func (s *sugaredLogger) Info(msg string, keyvals ...interface{}) {
+	s.l.Infow(msg, keyvals...)
+}
+
+func (s *sugaredLogger) Error(msg string, keyvals ...interface{}) {
+	s.l.Errorw(msg, keyvals...)
+}
@@ -1,72 +1,78 @@
 package pkg //nolint
 import (
-	"log"
+	"io"
 	"net/http"
 	"time"
 )
 
-// responseWriter is a minimal wrapper for http.ResponseWriter that allows the
-// written HTTP status code to be captured for logging.
-type responseWriter struct {
-	http.ResponseWriter
-	// headerMap http.Header
-	status      int
-	wroteHeader bool
-	size        int
+// countingReader counts the request body bytes read by the handler.
+type countingReader struct {
+	io.ReadCloser
+	size int64
 }
 
-func (rw *responseWriter) Status() int {
-	return rw.status
-}
-
-func (rw *responseWriter) Write(b []byte) (int, error) {
-	size, err := rw.ResponseWriter.Write(b) // write response using original http.ResponseWriter
-	rw.size += size                         // capture size
-	return size, err
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	if rw.wroteHeader {
-		return
-	}
-
-	rw.status = code
-	rw.ResponseWriter.WriteHeader(code)
-	rw.wroteHeader = true
-
-	return
-}
-
-func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.size += int64(n)
+	return n, err
 }
 
 type handler struct {
-	next http.Handler
+	next   http.Handler
+	logger Logger
 }
 
 func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	start := time.Now()
-	res := wrapResponseWriter(w)
-	h.next.ServeHTTP(res, req)
+	ctx, route := WithRouteInfo(req.Context())
+	ctx, bodies := withCapturedBodies(ctx)
+	req = req.WithContext(ctx)
+	var body *countingReader
+	if req.Body != nil {
+		body = &countingReader{ReadCloser: req.Body}
+		req.Body = body
+	}
+	res, rw := wrapResponseWriter(w)
+	h.next.ServeHTTP(rw, req)
 
-	cl := req.Header.Get("Content-Length")
-	if cl == "" {
-		cl = "0"
+	var bytesIn int64
+	if body != nil {
+		bytesIn = body.size
+	}
+	bytesOutUncompressed := res.size
+	if res.compressed {
+		bytesOutUncompressed = res.uncompressedSize
+	}
+	traceID := TraceIDFromContext(req.Context())
+	if traceID == "" {
+		traceID = res.ResponseWriter.Header().Get(TraceIDHeader)
+	}
+	if traceID == "" {
+		traceID = "N/A"
 	}
-	myHeader := req.Header.Get("my-header")
 
-	log.Println("my-header", myHeader)
-	log.Println("trace-id", res.ResponseWriter.Header().Get("x-trace-id"))
-	log.Println("method", req.Method)
-	log.Println("uri", req.RequestURI)
-	log.Println("status", res.status)
-	log.Println("latency", time.Since(start))
-	log.Println("bytes_in", cl)
-	log.Println("bytes_out", res.size)
-	log.Println("user-agent", req.UserAgent())
+	keyvals := []interface{}{
+		"trace_id", traceID,
+		"method", req.Method,
+		"route", route.Pattern,
+		"uri", req.RequestURI,
+		"status", res.status,
+		"latency", time.Since(start),
+		"bytes_in", bytesIn,
+		"bytes_out", res.size,
+		"bytes_out_uncompressed", bytesOutUncompressed,
+		"remote_addr", req.RemoteAddr,
+		"user_agent", req.UserAgent(),
+	}
+	if bodies.captured {
+		keyvals = append(keyvals, "request_body", bodies.request, "response_body", bodies.response)
+	}
+	h.logger.Info("http request", keyvals...)
 }
 
-func WithLoggingMiddleware(h http.Handler) http.Handler {
-	return &handler{next: h}
+// WithLoggingMiddleware writes one access log record per request.
+// Register RouteAnnotator on the wrapped mux to get the route pattern logged
+// and wrap the inner handlers with WithBodyCaptureMiddleware to get the bodies.
+func WithLoggingMiddleware(h http.Handler, logger Logger) http.Handler {
+	return &handler{next: h, logger: logger}
 }
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unmatchedRoute labels the requests which matched no gateway route,
+// raw URIs are never used as label values.
+const unmatchedRoute = "unmatched"
+
+// Metrics are the Prometheus metrics of the gateway HTTP layer and
+// the backend gRPC layer.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpLatency  *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
+
+	grpcHandled  *prometheus.CounterVec
+	grpcLatency  *prometheus.HistogramVec
+	grpcInFlight *prometheus.GaugeVec
+
+	panics *prometheus.CounterVec
+}
+
+// NewMetrics registers the metrics, with the Go and process collectors,
+// in a new registry.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_http_requests_total",
+			Help: "HTTP requests handled by the gateway.",
+		}, []string{"route", "method", "status"}),
+		httpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gateway_http_request_duration_seconds",
+			Help:    "HTTP request latency of the gateway.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		httpInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gateway_http_requests_in_flight",
+			Help: "HTTP requests being handled by the gateway.",
+		}, []string{"method"}),
+		grpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "RPCs completed by the gRPC server.",
+		}, []string{"service", "method", "code"}),
+		grpcLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "RPC latency of the gRPC server, the whole stream for streaming RPCs.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "method"}),
+		grpcInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "grpc_server_in_flight",
+			Help: "RPCs being handled by the gRPC server.",
+		}, []string{"service", "method"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "panics_recovered_total",
+			Help: "Panics recovered from the handlers, by layer: grpc or http.",
+		}, []string{"layer"}),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpLatency, m.httpInFlight,
+		m.grpcHandled, m.grpcLatency, m.grpcInFlight,
+		m.panics,
+	)
+	return m
+}
+
+// Registry lets other components add their metrics.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RecordPanic counts a recovered panic of the layer, "grpc" or "http".
+func (m *Metrics) RecordPanic(layer string) {
+	m.panics.WithLabelValues(layer).Inc()
+}
+
+type metricsHandler struct {
+	next    http.Handler
+	metrics *Metrics
+}
+
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx, route := WithRouteInfo(req.Context())
+	inFlight := h.metrics.httpInFlight.WithLabelValues(req.Method)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	res, rw := wrapResponseWriter(w)
+	h.next.ServeHTTP(rw, req.WithContext(ctx))
+
+	pattern := route.Pattern
+	if pattern == "" {
+		pattern = unmatchedRoute
+	}
+	h.metrics.httpRequests.WithLabelValues(pattern, req.Method, strconv.Itoa(res.status)).Inc()
+	h.metrics.httpLatency.WithLabelValues(pattern, req.Method).Observe(time.Since(start).Seconds())
+}
+
+// WithMetricsMiddleware counts the gateway requests by route pattern.
+// Register RouteAnnotator on the wrapped mux to get the route patterns.
+func WithMetricsMiddleware(h http.Handler, m *Metrics) http.Handler {
+	return &metricsHandler{next: h, metrics: m}
+}
+
+func (m *Metrics) observeRPC(fullMethod string, start time.Time, err error) {
+	service, method := path.Dir(fullMethod)[1:], path.Base(fullMethod)
+	m.grpcHandled.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	m.grpcLatency.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
+
+// UnaryServerInterceptor records the metrics of the unary RPCs.
+func (m *Metrics) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	inFlight := m.grpcInFlight.WithLabelValues(path.Dir(info.FullMethod)[1:], path.Base(info.FullMethod))
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	res, err := handler(ctx, req)
+	m.observeRPC(info.FullMethod, start, err)
+	return res, err
+}
+
+// StreamServerInterceptor records the metrics of the streaming RPCs.
+func (m *Metrics) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	inFlight := m.grpcInFlight.WithLabelValues(path.Dir(info.FullMethod)[1:], path.Base(info.FullMethod))
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	err := handler(srv, ss)
+	m.observeRPC(info.FullMethod, start, err)
+	return err
+}
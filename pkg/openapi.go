@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// swaggerUI are the assets of swagger-ui-dist, see the swagger-ui target of the Makefile.
+//
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var swaggerUI embed.FS
+
+// docsPage is the Swagger UI page of the spec served at /openapi.json,
+// the UI assets are served under /docs/ by the gateway itself.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>API docs</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// WithOpenAPIHandlers serves the OpenAPI spec at /openapi.json and its
+// Swagger UI at /docs in front of h.
+func WithOpenAPIHandlers(h http.Handler, spec []byte) http.Handler {
+	assets, err := fs.Sub(swaggerUI, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	docsAssets := http.StripPrefix("/docs/", http.FileServer(http.FS(assets)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/openapi.json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(spec)
+		case r.URL.Path == "/docs" || r.URL.Path == "/docs/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(docsPage))
+		case strings.HasPrefix(r.URL.Path, "/docs/"):
+			docsAssets.ServeHTTP(w, r)
+		default:
+			h.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// ProblemContentType is the RFC 7807 media type.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Fields after Instance are
+// extension members built from the gRPC status details.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	GRPCCode      string             `json:"grpcCode"`
+	TraceID       string             `json:"traceID,omitempty"`
+	Error         *ProblemError      `json:"error,omitempty"`
+	InvalidParams []ProblemViolation `json:"invalidParams,omitempty"`
+	ErrorInfo     *ProblemErrorInfo  `json:"errorInfo,omitempty"`
+	RetryAfter    string             `json:"retryAfter,omitempty"`
+}
+
+// ProblemError is the pb.Error detail.
+type ProblemError struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// ProblemViolation is an errdetails.BadRequest field violation.
+type ProblemViolation struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ProblemErrorInfo is the errdetails.ErrorInfo detail.
+type ProblemErrorInfo struct {
+	Reason   string            `json:"reason"`
+	Domain   string            `json:"domain"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewProblem builds the problem details from st and every one of its details.
+// httpStatus is the response status, instance identifies the request.
+func NewProblem(st *status.Status, httpStatus int, instance string) *Problem {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(httpStatus),
+		Status:   httpStatus,
+		Detail:   st.Message(),
+		Instance: instance,
+		GRPCCode: st.Code().String(),
+	}
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *pb.Error:
+			p.Error = &ProblemError{Code: d.Code, Message: d.Message}
+			if d.Message != "" {
+				p.Detail = d.Message
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				p.InvalidParams = append(p.InvalidParams, ProblemViolation{Name: v.Field, Reason: v.Description})
+			}
+		case *errdetails.ErrorInfo:
+			p.ErrorInfo = &ProblemErrorInfo{Reason: d.Reason, Domain: d.Domain, Metadata: d.Metadata}
+		case *errdetails.RetryInfo:
+			if d.RetryDelay != nil {
+				p.RetryAfter = d.RetryDelay.AsDuration().String()
+			}
+		}
+	}
+	return p
+}
+
+// AcceptsProblem reports whether the client prefers application/problem+json
+// over application/json according to the Accept header.
+func AcceptsProblem(r *http.Request) bool {
+	problemQ, jsonQ := -1.0, -1.0
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+			switch mediaType {
+			case ProblemContentType:
+				problemQ = q
+			case "application/json":
+				jsonQ = q
+			}
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
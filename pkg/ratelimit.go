@@ -0,0 +1,304 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// RateLimit is a token bucket: Burst tokens at most, refilled at Rate per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult is the state of the bucket after a request took a token.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the wait for the next token when the request is not allowed
+	RetryAfter time.Duration
+	// Reset is the wait until the bucket is full again
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the token buckets, the buckets of a store shared by
+// several gateway instances limit the clients across them.
+type RateLimitStore interface {
+	// Take takes a token from the bucket of the key
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// MemoryRateLimitStore keeps the buckets of a single gateway instance.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// sweepInterval is how often the full buckets are dropped, a full bucket
+// is the same as a missing one.
+const sweepInterval = time.Minute
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, b := range s.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	res := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return res, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimitRule limits the requests of a route pattern per client.
+type RateLimitRule struct {
+	// Pattern is the google.api.http path template, e.g. "/v1/echo/{name}"
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Method is the HTTP method, all methods when empty
+	Method string  `yaml:"method" json:"method"`
+	Rate   float64 `yaml:"rate" json:"rate"`
+	Burst  int     `yaml:"burst" json:"burst"`
+}
+
+// DefaultRateLimitMaxHeaderKeys is the header values limit used when
+// RateLimitConfig.MaxHeaderKeys is zero.
+const DefaultRateLimitMaxHeaderKeys = 10000
+
+// RateLimitConfig is the rate limiting configuration of the gateway.
+type RateLimitConfig struct {
+	// Key identifies the client: "ip", "subject" or "header:<name>", e.g.
+	// "header:x-api-key". The subject is the one of the credentials verified
+	// like the gRPC server does. The client IP is used when the subject or
+	// the header is missing, and for the anonymous subject.
+	Key string `yaml:"key" json:"key"`
+	// MaxHeaderKeys is the most header values with their own buckets. The
+	// header is not verified, so once MaxHeaderKeys values got buckets the
+	// clients sending other values are limited by their IP.
+	MaxHeaderKeys int             `yaml:"max_header_keys" json:"max_header_keys"`
+	Rules         []RateLimitRule `yaml:"rules" json:"rules"`
+}
+
+// LoadRateLimitConfig reads the rate limit config from a YAML or JSON file.
+func LoadRateLimitConfig(file string) (RateLimitConfig, error) {
+	var cfg RateLimitConfig
+	if err := loadConfig(file, &cfg); err != nil {
+		return cfg, fmt.Errorf("load rate limit config %s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+type rateLimitRule struct {
+	RateLimitRule
+	path pathTemplate
+}
+
+type rateLimitHandler struct {
+	next         http.Handler
+	store        RateLimitStore
+	verifier     Verifier
+	header       string
+	rules        []rateLimitRule
+	errorHandler func(http.ResponseWriter, *http.Request, error)
+	logger       Logger
+
+	// headerKeys are the header values with buckets, they are kept so that
+	// the values sent past maxHeaderKeys can't take the buckets of others
+	mu            sync.Mutex
+	headerKeys    map[string]struct{}
+	maxHeaderKeys int
+}
+
+// trackHeaderKey reports whether the header value gets its own buckets.
+func (h *rateLimitHandler) trackHeaderKey(v string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.headerKeys[v]; ok {
+		return true
+	}
+	if len(h.headerKeys) >= h.maxHeaderKeys {
+		return false
+	}
+	h.headerKeys[v] = struct{}{}
+	return true
+}
+
+// subject returns the subject of the verified credentials of the request,
+// it is empty for the requests without valid credentials.
+func (h *rateLimitHandler) subject(r *http.Request) string {
+	md := make(metadata.MD, len(r.Header))
+	for k, v := range r.Header {
+		md[strings.ToLower(k)] = v
+	}
+	p, err := h.verifier.Verify(md)
+	if err != nil || p.Subject == AnonymousSubject {
+		return ""
+	}
+	return p.Subject
+}
+
+func (h *rateLimitHandler) clientKey(r *http.Request) string {
+	if h.verifier != nil {
+		if s := h.subject(r); s != "" {
+			return "subject=" + s
+		}
+	}
+	if h.header != "" {
+		if v := r.Header.Get(h.header); v != "" && h.trackHeaderKey(v) {
+			return h.header + "=" + v
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip=" + host
+}
+
+func (h *rateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var rule *rateLimitRule
+	for i := range h.rules {
+		if (h.rules[i].Method == "" || h.rules[i].Method == r.Method) && h.rules[i].path.match(r.URL.Path) {
+			rule = &h.rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	// the rejected requests never reach the mux, name their route here
+	if route, ok := RouteInfoFromContext(r.Context()); ok && route.Pattern == "" {
+		route.Pattern = rule.Pattern
+	}
+
+	limit := RateLimit{Rate: rule.Rate, Burst: rule.Burst}
+	res, err := h.store.Take(r.Context(), rule.Method+" "+rule.Pattern+" "+h.clientKey(r), limit)
+	if err != nil {
+		// a broken store must not take the gateway down, the request passes
+		h.logger.Error("rate limit store failed", "route", rule.Pattern, "error", err)
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Burst))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(res.Reset.Seconds())), 10))
+	if res.Allowed {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	st, derr := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(
+		&pb.Error{Code: http.StatusTooManyRequests, Message: "rate limit exceeded"},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(res.RetryAfter)},
+	)
+	if derr != nil {
+		st = status.New(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	h.errorHandler(w, r, st.Err())
+}
+
+// WithRateLimitMiddleware limits the requests per client with token buckets
+// kept in store, the first rule matching the request applies and the requests
+// matching no rule are not limited. The limiting runs before the gRPC server
+// authenticates the request, the "subject" key verifies the credentials with
+// verifier, which may be nil for the other keys. The header keys are
+// unverified values and at most cfg.MaxHeaderKeys of them get buckets. The
+// responses of the limited routes get the X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset headers. The rejected requests
+// are ResourceExhausted errors written by errorHandler.
+func WithRateLimitMiddleware(h http.Handler, cfg RateLimitConfig, store RateLimitStore, verifier Verifier, errorHandler func(http.ResponseWriter, *http.Request, error), logger Logger) (http.Handler, error) {
+	res := &rateLimitHandler{
+		next:          h,
+		store:         store,
+		errorHandler:  errorHandler,
+		logger:        logger,
+		headerKeys:    make(map[string]struct{}),
+		maxHeaderKeys: cfg.MaxHeaderKeys,
+	}
+	if cfg.MaxHeaderKeys < 0 {
+		return nil, fmt.Errorf("rate limit max header keys %d is negative", cfg.MaxHeaderKeys)
+	}
+	if res.maxHeaderKeys == 0 {
+		res.maxHeaderKeys = DefaultRateLimitMaxHeaderKeys
+	}
+	switch {
+	case cfg.Key == "" || cfg.Key == "ip":
+	case cfg.Key == "subject":
+		if verifier == nil {
+			return nil, fmt.Errorf("rate limit key %q needs a verifier", cfg.Key)
+		}
+		res.verifier = verifier
+	case strings.HasPrefix(cfg.Key, "header:") && len(cfg.Key) > len("header:"):
+		res.header = strings.TrimPrefix(cfg.Key, "header:")
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", cfg.Key)
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Rate <= 0 || rule.Burst < 1 {
+			return nil, fmt.Errorf("rate limit of %s needs a positive rate and burst", rule.Pattern)
+		}
+		rule.Method = strings.ToUpper(rule.Method)
+		path, err := parsePathTemplate(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit rule: %w", err)
+		}
+		res.rules = append(res.rules, rateLimitRule{RateLimitRule: rule, path: path})
+	}
+	return res, nil
+}
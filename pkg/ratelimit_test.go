@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitClientKey(t *testing.T) {
+	verifier := NewAPIKeyVerifier("x-api-key", []APIKey{{Key: "k1", Subject: "alice"}}, false)
+	tests := []struct {
+		name   string
+		cfg    RateLimitConfig
+		header string
+		want   string
+	}{
+		{"subject", RateLimitConfig{Key: "subject"}, "k1", "subject=alice"},
+		{"unknown key", RateLimitConfig{Key: "subject"}, "forged", "ip=192.0.2.1"},
+		{"no credentials", RateLimitConfig{Key: "subject"}, "", "ip=192.0.2.1"},
+		{"header", RateLimitConfig{Key: "header:x-api-key", MaxHeaderKeys: 1}, "forged", "x-api-key=forged"},
+		{"ip", RateLimitConfig{Key: "ip"}, "k1", "ip=192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := WithRateLimitMiddleware(http.NotFoundHandler(), tt.cfg, NewMemoryRateLimitStore(), verifier, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.header != "" {
+				r.Header.Set("X-Api-Key", tt.header)
+			}
+			if got := h.(*rateLimitHandler).clientKey(r); got != tt.want {
+				t.Errorf("clientKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaderKeysCap(t *testing.T) {
+	h, err := WithRateLimitMiddleware(http.NotFoundHandler(), RateLimitConfig{Key: "header:x-api-key", MaxHeaderKeys: 2}, NewMemoryRateLimitStore(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(v string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("X-Api-Key", v)
+		return h.(*rateLimitHandler).clientKey(r)
+	}
+	for _, v := range []string{"a", "b", "c", "a", "b", "c"} {
+		want := "x-api-key=" + v
+		// the values past the cap share the IP bucket, the first ones keep theirs
+		if v == "c" {
+			want = "ip=192.0.2.1"
+		}
+		if got := key(v); got != want {
+			t.Errorf("clientKey(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// Recovery turns the panics of the handlers into Internal errors, the panic
+// and its stack are logged with the trace id and counted in the metrics.
+type Recovery struct {
+	logger  Logger
+	metrics *Metrics
+}
+
+// NewRecovery creates the recovery, metrics may be nil.
+func NewRecovery(logger Logger, metrics *Metrics) *Recovery {
+	return &Recovery{logger: logger, metrics: metrics}
+}
+
+func (rc *Recovery) record(ctx context.Context, layer string, p interface{}, attrs ...interface{}) {
+	if rc.metrics != nil {
+		rc.metrics.RecordPanic(layer)
+	}
+	keyvals := append([]interface{}{
+		"trace_id", TraceIDFromContext(ctx),
+		"layer", layer,
+		"panic", fmt.Sprint(p),
+		"stack", string(debug.Stack()),
+	}, attrs...)
+	rc.logger.Error("panic recovered", keyvals...)
+}
+
+// internalError hides the panic value from the client.
+func internalError() error {
+	st, err := status.New(codes.Internal, "internal error").
+		WithDetails(&pb.Error{Code: http.StatusInternalServerError, Message: "internal error"})
+	if err != nil {
+		return status.Error(codes.Internal, "internal error")
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor recovers the panics of the unary handlers.
+func (rc *Recovery) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			rc.record(ctx, "grpc", p, "full_method", info.FullMethod)
+			res, err = nil, internalError()
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor recovers the panics of the streaming handlers.
+func (rc *Recovery) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			rc.record(ss.Context(), "grpc", p, "full_method", info.FullMethod)
+			err = internalError()
+		}
+	}()
+	return handler(srv, ss)
+}
+
+type recoveryHandler struct {
+	next         http.Handler
+	recovery     *Recovery
+	errorHandler func(http.ResponseWriter, *http.Request, error)
+}
+
+func (h *recoveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res, rw := wrapResponseWriter(w)
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p == http.ErrAbortHandler {
+			// the server aborts the response on purpose, it logs nothing
+			panic(p)
+		}
+		h.recovery.record(r.Context(), "http", p, "method", r.Method, "uri", r.RequestURI)
+		// the client got a part of the response already, it can't be fixed
+		if res.wroteHeader {
+			panic(http.ErrAbortHandler)
+		}
+		h.errorHandler(rw, r, internalError())
+	}()
+	h.next.ServeHTTP(rw, r)
+}
+
+// Middleware recovers the panics of h, the Internal error is written by
+// errorHandler. A panic after the response started aborts the connection.
+func (rc *Recovery) Middleware(h http.Handler, errorHandler func(http.ResponseWriter, *http.Request, error)) http.Handler {
+	return &recoveryHandler{next: h, recovery: rc, errorHandler: errorHandler}
+}
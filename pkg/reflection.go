@@ -0,0 +1,17 @@
+package pkg
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	rpbv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// RegisterReflection registers the v1 and v1alpha server reflection on s,
+// listing the services of described rather than the ones of s, so the admin
+// listener describes the gRPC listener without serving its services.
+func RegisterReflection(s *grpc.Server, described reflection.ServiceInfoProvider) {
+	opts := reflection.ServerOptions{Services: described}
+	rpbv1.RegisterServerReflectionServer(s, reflection.NewServerV1(opts))
+	rpb.RegisterServerReflectionServer(s, reflection.NewServer(opts))
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	rpbv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// describedServiceDesc is a service without methods, only its name is listed.
+var describedServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Described",
+	HandlerType: (*interface{})(nil),
+}
+
+func TestReflectionListsDescribedServer(t *testing.T) {
+	described := grpc.NewServer()
+	described.RegisterService(&describedServiceDesc, struct{}{})
+	// admin serves the reflection only, it lists the services of described
+	admin := grpc.NewServer()
+	RegisterReflection(admin, described)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = admin.Serve(lis) }()
+	defer admin.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("v1", func(t *testing.T) {
+		stream, err := rpbv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &rpbv1.ServerReflectionRequest{MessageRequest: &rpbv1.ServerReflectionRequest_ListServices{}}
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := res.GetListServicesResponse().GetService(); len(s) != 1 || s[0].Name != "test.Described" {
+			t.Errorf("services = %v, want only test.Described", s)
+		}
+	})
+	t.Run("v1alpha", func(t *testing.T) {
+		stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}}
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := res.GetListServicesResponse().GetService(); len(s) != 1 || s[0].Name != "test.Described" {
+			t.Errorf("services = %v, want only test.Described", s)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package pkg
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter is a minimal wrapper for http.ResponseWriter that allows the
+// written HTTP status code to be captured for logging.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	size        int
+	// compressed is set by the compression middleware, size counts the
+	// compressed bytes then and uncompressedSize the bytes of the handler
+	compressed       bool
+	uncompressedSize int
+	// capture gets a copy of the body when it is set
+	capture *captureBuffer
+}
+
+func (rw *responseWriter) Status() int {
+	return rw.status
+}
+
+// stats lets the inner middlewares find the wrapper behind the
+// interface combinations of wrapResponseWriter.
+func (rw *responseWriter) stats() *responseWriter {
+	return rw
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	// the first Write sends the header with 200 implicitly
+	rw.WriteHeader(http.StatusOK)
+	size, err := rw.ResponseWriter.Write(b) // write response using original http.ResponseWriter
+	rw.size += size                         // capture size
+	if rw.capture != nil {
+		_, _ = rw.capture.Write(b[:size])
+	}
+	return size, err
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+	rw.wroteHeader = true
+}
+
+// rwFlusher lets the gateway stream responses through the wrapper.
+type rwFlusher struct{ rw *responseWriter }
+
+func (f rwFlusher) Flush() {
+	// flushing sends the header with 200 implicitly
+	f.rw.WriteHeader(http.StatusOK)
+	f.rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+// rwHijacker lets the WebSocket proxy take over the connection.
+type rwHijacker struct{ rw *responseWriter }
+
+func (h rwHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := h.rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && !h.rw.wroteHeader {
+		// the handler answers on the connection itself, e.g. 101 Switching Protocols
+		h.rw.status = http.StatusSwitchingProtocols
+		h.rw.wroteHeader = true
+	}
+	return conn, buf, err
+}
+
+// rwPusher keeps the HTTP/2 server push.
+type rwPusher struct{ rw *responseWriter }
+
+func (p rwPusher) Push(target string, opts *http.PushOptions) error {
+	return p.rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// rwReaderFrom keeps the sendfile path of io.Copy, the copied bytes are counted.
+type rwReaderFrom struct{ rw *responseWriter }
+
+func (r rwReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.rw.WriteHeader(http.StatusOK)
+	if r.rw.capture != nil {
+		src = io.TeeReader(src, r.rw.capture)
+	}
+	n, err := r.rw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.rw.size += int(n)
+	return n, err
+}
+
+// wrapResponseWriter wraps w, the returned http.ResponseWriter is the one
+// passed to the next handler. It implements exactly the optional interfaces
+// w implements among http.Flusher, http.Hijacker, http.Pusher and
+// io.ReaderFrom, so the handlers type-asserting them behave as without the wrapper.
+func wrapResponseWriter(w http.ResponseWriter) (*responseWriter, http.ResponseWriter) {
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+	const (
+		flusher = 1 << iota
+		hijacker
+		pusher
+		readerFrom
+	)
+	var mask int
+	if _, ok := w.(http.Flusher); ok {
+		mask |= flusher
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		mask |= hijacker
+	}
+	if _, ok := w.(http.Pusher); ok {
+		mask |= pusher
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		mask |= readerFrom
+	}
+
+	f, h, p, r := rwFlusher{rw}, rwHijacker{rw}, rwPusher{rw}, rwReaderFrom{rw}
+	switch mask {
+	case flusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+		}{rw, f}
+	case hijacker:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+		}{rw, h}
+	case flusher | hijacker:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, f, h}
+	case pusher:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+		}{rw, p}
+	case flusher | pusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{rw, f, p}
+	case hijacker | pusher:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, h, p}
+	case flusher | hijacker | pusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, f, h, p}
+	case readerFrom:
+		return rw, struct {
+			*responseWriter
+			io.ReaderFrom
+		}{rw, r}
+	case flusher | readerFrom:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{rw, f, r}
+	case hijacker | readerFrom:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, h, r}
+	case flusher | hijacker | readerFrom:
+		// the HTTP/1.1 writer of net/http
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, f, h, r}
+	case pusher | readerFrom:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+			io.ReaderFrom
+		}{rw, p, r}
+	case flusher | pusher | readerFrom:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{rw, f, p, r}
+	case hijacker | pusher | readerFrom:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{rw, h, p, r}
+	case flusher | hijacker | pusher | readerFrom:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{rw, f, h, p, r}
+	}
+	return rw, rw
+}
@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// the fakes implement one optional interface each on top of rec
+type (
+	fakeFlusher    struct{ rec *httptest.ResponseRecorder }
+	fakeHijacker   struct{ rec *httptest.ResponseRecorder }
+	fakePusher     struct{ rec *httptest.ResponseRecorder }
+	fakeReaderFrom struct{ rec *httptest.ResponseRecorder }
+)
+
+func (f fakeFlusher) Flush() { f.rec.Flush() }
+
+func (f fakeHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, _ := net.Pipe()
+	return c, bufio.NewReadWriter(bufio.NewReader(c), bufio.NewWriter(c)), nil
+}
+
+func (f fakePusher) Push(string, *http.PushOptions) error { return nil }
+
+func (f fakeReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f.rec}, src)
+}
+
+const (
+	maskFlusher = 1 << iota
+	maskHijacker
+	maskPusher
+	maskReaderFrom
+)
+
+// fakeWriter returns a writer implementing exactly the interfaces of mask.
+func fakeWriter(mask int) (http.ResponseWriter, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	// the embedded interface hides the Flush method of the recorder
+	w := struct{ http.ResponseWriter }{rec}
+	f, h, p, r := fakeFlusher{rec}, fakeHijacker{rec}, fakePusher{rec}, fakeReaderFrom{rec}
+	switch mask {
+	case 0:
+		return w, rec
+	case maskFlusher:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+		}{w, f}, rec
+	case maskHijacker:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+		}{w, h}, rec
+	case maskFlusher | maskHijacker:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+		}{w, f, h}, rec
+	case maskPusher:
+		return struct {
+			http.ResponseWriter
+			http.Pusher
+		}{w, p}, rec
+	case maskFlusher | maskPusher:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Pusher
+		}{w, f, p}, rec
+	case maskHijacker | maskPusher:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			http.Pusher
+		}{w, h, p}, rec
+	case maskFlusher | maskHijacker | maskPusher:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{w, f, h, p}, rec
+	case maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			io.ReaderFrom
+		}{w, r}, rec
+	case maskFlusher | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{w, f, r}, rec
+	case maskHijacker | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{w, h, r}, rec
+	case maskFlusher | maskHijacker | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{w, f, h, r}, rec
+	case maskPusher | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Pusher
+			io.ReaderFrom
+		}{w, p, r}, rec
+	case maskFlusher | maskPusher | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{w, f, p, r}, rec
+	case maskHijacker | maskPusher | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{w, h, p, r}, rec
+	case maskFlusher | maskHijacker | maskPusher | maskReaderFrom:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{w, f, h, p, r}, rec
+	}
+	panic("unknown mask")
+}
+
+func interfaceMask(w http.ResponseWriter) int {
+	var mask int
+	if _, ok := w.(http.Flusher); ok {
+		mask |= maskFlusher
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		mask |= maskHijacker
+	}
+	if _, ok := w.(http.Pusher); ok {
+		mask |= maskPusher
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		mask |= maskReaderFrom
+	}
+	return mask
+}
+
+func TestWrapResponseWriterInterfaces(t *testing.T) {
+	for mask := 0; mask < 16; mask++ {
+		w, _ := fakeWriter(mask)
+		if got := interfaceMask(w); got != mask {
+			t.Fatalf("fakeWriter(%04b) implements %04b", mask, got)
+		}
+		_, wrapped := wrapResponseWriter(w)
+		if got := interfaceMask(wrapped); got != mask {
+			t.Errorf("wrapped %04b writer implements %04b", mask, got)
+		}
+	}
+}
+
+func TestWrapResponseWriterImplicitStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		mask  int
+		write func(w http.ResponseWriter) error
+		want  int
+	}{
+		{"Write", 0, func(w http.ResponseWriter) error {
+			_, err := w.Write([]byte("body"))
+			return err
+		}, http.StatusOK},
+		{"Flush", maskFlusher, func(w http.ResponseWriter) error {
+			w.(http.Flusher).Flush()
+			return nil
+		}, http.StatusOK},
+		{"ReadFrom", maskReaderFrom, func(w http.ResponseWriter) error {
+			_, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("body"))
+			return err
+		}, http.StatusOK},
+		{"Hijack", maskHijacker, func(w http.ResponseWriter) error {
+			c, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = c.Close()
+			}
+			return err
+		}, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, rec := fakeWriter(tt.mask)
+			res, wrapped := wrapResponseWriter(w)
+			// zero statuses tell the implicit ones from the defaults
+			res.status, rec.Code = 0, 0
+			if err := tt.write(wrapped); err != nil {
+				t.Fatal(err)
+			}
+			if !res.wroteHeader || res.Status() != tt.want {
+				t.Errorf("status = %d, wroteHeader = %v, want %d", res.Status(), res.wroteHeader, tt.want)
+			}
+			// the hijacked connection gets no header from the wrapper
+			wantSent := tt.want
+			if tt.want == http.StatusSwitchingProtocols {
+				wantSent = 0
+			}
+			if rec.Code != wantSent {
+				t.Errorf("sent status = %d, want %d", rec.Code, wantSent)
+			}
+			// the handler can't change the status afterwards
+			wrapped.WriteHeader(http.StatusTeapot)
+			if res.Status() != tt.want {
+				t.Errorf("status after WriteHeader = %d, want %d", res.Status(), tt.want)
+			}
+		})
+	}
+}
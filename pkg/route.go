@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// RouteInfo is filled by the gateway mux while the request is routed.
+type RouteInfo struct {
+	// RPCMethod is the full method name, e.g. "/echo.EchoService/GetEcho"
+	RPCMethod string
+	// Pattern is the google.api.http path template, e.g. "/v1/echo/{name}"
+	Pattern string
+}
+
+type routeInfoKey struct{}
+
+// WithRouteInfo returns the request context which collects the route info.
+func WithRouteInfo(ctx context.Context) (context.Context, *RouteInfo) {
+	if info, ok := RouteInfoFromContext(ctx); ok {
+		return ctx, info
+	}
+	info := &RouteInfo{}
+	return context.WithValue(ctx, routeInfoKey{}, info), info
+}
+
+func RouteInfoFromContext(ctx context.Context) (*RouteInfo, bool) {
+	info, ok := ctx.Value(routeInfoKey{}).(*RouteInfo)
+	return info, ok
+}
+
+// RouteAnnotator records the matched route into the RouteInfo of the request,
+// register it with runtime.WithMetadata. It adds no metadata.
+func RouteAnnotator(ctx context.Context, r *http.Request) metadata.MD {
+	info, ok := RouteInfoFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	if rpcMethod, ok := runtime.RPCMethod(ctx); ok {
+		info.RPCMethod = rpcMethod
+		info.Pattern = RoutePattern(rpcMethod, r.Method)
+	}
+	return nil
+}
+
+var routePatterns sync.Map // "METHOD /pkg.Service/Method" -> pattern
+
+// RoutePattern returns the google.api.http path template bound to the gRPC
+// method for the HTTP method, or the gRPC method itself when it is unknown.
+func RoutePattern(rpcMethod, httpMethod string) string {
+	key := httpMethod + " " + rpcMethod
+	if p, ok := routePatterns.Load(key); ok {
+		return p.(string)
+	}
+	pattern := lookupRoutePattern(rpcMethod, httpMethod)
+	routePatterns.Store(key, pattern)
+	return pattern
+}
+
+func lookupRoutePattern(rpcMethod, httpMethod string) string {
+	name := protoreflect.FullName(strings.Replace(strings.TrimPrefix(rpcMethod, "/"), "/", ".", 1))
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return rpcMethod
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return rpcMethod
+	}
+	rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return rpcMethod
+	}
+	for _, r := range append([]*annotations.HttpRule{rule}, rule.AdditionalBindings...) {
+		if method, path := httpRuleBinding(r); method == httpMethod {
+			return path
+		}
+	}
+	return rpcMethod
+}
+
+// httpRuleBinding returns the HTTP method and the path template of the rule.
+func httpRuleBinding(rule *annotations.HttpRule) (string, string) {
+	switch p := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		return p.Custom.GetKind(), p.Custom.GetPath()
+	}
+	return "", ""
+}
+
+// pathTemplate is a google.api.http path template with its variables
+// expanded into their segments: "{name}" is "*" and "{name=shelves/*}" is
+// "shelves" and "*", see the template syntax in google/api/http.proto.
+type pathTemplate struct {
+	// segments are literals, "*" matching one segment and a last "**"
+	// matching the rest of the path
+	segments []string
+	verb     string
+}
+
+// parsePathTemplate parses the template, e.g. "/v1/{name=shelves/*}:publish".
+func parsePathTemplate(tmpl string) (pathTemplate, error) {
+	var t pathTemplate
+	if !strings.HasPrefix(tmpl, "/") {
+		return t, fmt.Errorf("path template %q doesn't start with /", tmpl)
+	}
+	rest := tmpl[1:]
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "}") && i > strings.LastIndex(rest, "/") {
+		rest, t.verb = rest[:i], rest[i+1:]
+	}
+	for rest != "" {
+		var segs []string
+		if rest[0] == '{' {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return t, fmt.Errorf("path template %q has an unclosed variable", tmpl)
+			}
+			field, value := rest[1:end], "*"
+			if i := strings.IndexByte(field, '='); i >= 0 {
+				field, value = field[:i], field[i+1:]
+			}
+			if field == "" {
+				return t, fmt.Errorf("path template %q has a variable without a field", tmpl)
+			}
+			segs, rest = strings.Split(value, "/"), rest[end+1:]
+		} else {
+			end := strings.IndexByte(rest, '/')
+			if end < 0 {
+				end = len(rest)
+			}
+			segs, rest = []string{rest[:end]}, rest[end:]
+		}
+		for _, seg := range segs {
+			if seg == "" || strings.ContainsAny(seg, "{}=") {
+				return t, fmt.Errorf("path template %q has an invalid segment %q", tmpl, seg)
+			}
+		}
+		t.segments = append(t.segments, segs...)
+		if rest == "" {
+			break
+		}
+		if rest[0] != '/' || len(rest) == 1 {
+			return t, fmt.Errorf("path template %q has an invalid segment after %q", tmpl, t.segments[len(t.segments)-1])
+		}
+		rest = rest[1:]
+	}
+	for i, seg := range t.segments {
+		if seg == "**" && i != len(t.segments)-1 {
+			return t, fmt.Errorf("path template %q has ** before the last segment", tmpl)
+		}
+	}
+	return t, nil
+}
+
+// match reports whether the path fits the template.
+func (t pathTemplate) match(path string) bool {
+	if t.verb != "" {
+		if !strings.HasSuffix(path, ":"+t.verb) {
+			return false
+		}
+		path = strings.TrimSuffix(path, ":"+t.verb)
+	}
+	var parts []string
+	if path = strings.Trim(path, "/"); path != "" {
+		parts = strings.Split(path, "/")
+	}
+	for i, seg := range t.segments {
+		if seg == "**" {
+			return true
+		}
+		if i >= len(parts) || parts[i] == "" {
+			return false
+		}
+		if seg != "*" && seg != parts[i] {
+			return false
+		}
+	}
+	return len(parts) == len(t.segments)
+}
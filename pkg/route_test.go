@@ -0,0 +1,60 @@
+package pkg
+
+import "testing"
+
+func TestPathTemplateMatch(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		path string
+		want bool
+	}{
+		{"/v1/echo", "/v1/echo", true},
+		{"/v1/echo", "/v1/echo/abc", false},
+		{"/v1/*", "/v1/echo", true},
+		{"/v1/*", "/v1", false},
+		{"/v1/*", "/v1/echo/abc", false},
+		{"/v1/**", "/v1", true},
+		{"/v1/**", "/v1/echo/abc", true},
+		{"/v1/**", "/v2/echo", false},
+		{"/v1/echo/{name}", "/v1/echo/abc", true},
+		{"/v1/echo/{name}", "/v1/echo/abc/stream", false},
+		{"/v1/echo/{name}/stream", "/v1/echo/abc/stream", true},
+		{"/v1/{name=shelves/*}", "/v1/shelves/1", true},
+		{"/v1/{name=shelves/*}", "/v1/shelves", false},
+		{"/v1/{name=shelves/*}", "/v1/books/1", false},
+		{"/v1/{name=shelves/*}", "/v1/shelves/1/books", false},
+		{"/v1/{name=shelves/*}/books", "/v1/shelves/1/books", true},
+		{"/v1/{name=shelves/**}", "/v1/shelves/1/books/2", true},
+		{"/v1/{name=shelves/*}:publish", "/v1/shelves/1:publish", true},
+		{"/v1/{name=shelves/*}:publish", "/v1/shelves/1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl+" "+tt.path, func(t *testing.T) {
+			tmpl, err := parsePathTemplate(tt.tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tmpl.match(tt.path); got != tt.want {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePathTemplateErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		"v1/echo",
+		"/v1/echo/",
+		"/v1//echo",
+		"/v1/{name",
+		"/v1/{=shelves/*}",
+		"/v1/{name}abc",
+		"/v1/{name={id}}",
+		"/v1/**/echo",
+		"/v1/{name=**}/echo",
+	} {
+		if _, err := parsePathTemplate(tmpl); err == nil {
+			t.Errorf("parsePathTemplate(%q) accepted the template", tmpl)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc/codes"
+)
+
+// IsGRPCRequest reports whether r is a gRPC call: HTTP/2 with the
+// application/grpc content type.
+func IsGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// SinglePort serves gRPC and the REST gateway on one listener.
+// gRPC calls go to grpcServer, everything else goes to gateway.
+// Plaintext HTTP/2 is accepted with h2c, under TLS the http.Server
+// negotiates h2 with ALPN by itself.
+//
+// The gRPC calls served this way are not drained by grpc.Server.GracefulStop,
+// which panics on them, and the h2c connections are hijacked, so
+// http.Server.Shutdown does not wait for them either. Call Drain and then
+// grpc.Server.Stop instead.
+type SinglePort struct {
+	grpcServer http.Handler
+	gateway    http.Handler
+	handler    http.Handler
+
+	mu       sync.Mutex
+	calls    int
+	draining bool
+	idle     chan struct{}
+}
+
+func NewSinglePort(grpcServer http.Handler, gateway http.Handler) *SinglePort {
+	p := &SinglePort{grpcServer: grpcServer, gateway: gateway, idle: make(chan struct{})}
+	p.handler = h2c.NewHandler(http.HandlerFunc(p.serve), &http2.Server{})
+	return p
+}
+
+func (p *SinglePort) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.handler.ServeHTTP(w, r)
+}
+
+func (p *SinglePort) serve(w http.ResponseWriter, r *http.Request) {
+	if !IsGRPCRequest(r) {
+		p.gateway.ServeHTTP(w, r)
+		return
+	}
+	if !p.begin() {
+		// a trailers-only response, the client may retry elsewhere
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unavailable)))
+		w.Header().Set("Grpc-Message", "server is shutting down")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	defer p.end()
+	p.grpcServer.ServeHTTP(w, r)
+}
+
+func (p *SinglePort) begin() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return false
+	}
+	p.calls++
+	return true
+}
+
+func (p *SinglePort) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls--
+	if p.draining && p.calls == 0 {
+		close(p.idle)
+	}
+}
+
+// Drain refuses the new gRPC calls with Unavailable and waits for the
+// in-flight ones until ctx is done. It reports whether they all finished.
+func (p *SinglePort) Drain(ctx context.Context) bool {
+	p.mu.Lock()
+	if !p.draining {
+		p.draining = true
+		if p.calls == 0 {
+			close(p.idle)
+		}
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.idle:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
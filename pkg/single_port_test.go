@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// slowService holds its calls until release is closed.
+type slowService struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+var slowServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Slow",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Call",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+			if err := dec(&emptypb.Empty{}); err != nil {
+				return nil, err
+			}
+			s := srv.(*slowService)
+			s.entered <- struct{}{}
+			<-s.release
+			return &emptypb.Empty{}, nil
+		},
+	}},
+}
+
+func startSinglePort(t *testing.T) (*SinglePort, *grpc.Server, *slowService, *grpc.ClientConn) {
+	t.Helper()
+	svc := &slowService{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	s := grpc.NewServer()
+	s.RegisterService(&slowServiceDesc, svc)
+	p := NewSinglePort(s, http.NotFoundHandler())
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+
+	conn, err := grpc.Dial(srv.Listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return p, s, svc, conn
+}
+
+func callSlow(ctx context.Context, conn *grpc.ClientConn) error {
+	return conn.Invoke(ctx, "/test.Slow/Call", &emptypb.Empty{}, &emptypb.Empty{})
+}
+
+// GracefulStop panics on the calls served through grpc.Server.ServeHTTP,
+// Drain followed by Stop must finish the in-flight call instead.
+func TestSinglePortDrainWaitsForInFlightCalls(t *testing.T) {
+	p, s, svc, conn := startSinglePort(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	callErr := make(chan error, 1)
+	go func() { callErr <- callSlow(ctx, conn) }()
+	<-svc.entered
+
+	drained := make(chan bool, 1)
+	go func() { drained <- p.Drain(ctx) }()
+	select {
+	case <-drained:
+		t.Fatal("Drain returned while a call is in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := callSlow(ctx, conn); status.Code(err) != codes.Unavailable {
+		t.Fatalf("new call while draining: got %v, want Unavailable", err)
+	}
+
+	close(svc.release)
+	if err := <-callErr; err != nil {
+		t.Fatalf("in-flight call: %v", err)
+	}
+	if !<-drained {
+		t.Fatal("Drain reported the calls not finished")
+	}
+	s.Stop()
+}
+
+func TestSinglePortDrainTimeout(t *testing.T) {
+	p, s, svc, conn := startSinglePort(t)
+	defer s.Stop()
+	defer close(svc.release)
+
+	go func() { _ = callSlow(context.Background(), conn) }()
+	<-svc.entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if p.Drain(ctx) {
+		t.Fatal("Drain reported the calls finished while one is held")
+	}
+}
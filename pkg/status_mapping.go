@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// DefaultRetryAfter is sent in the Retry-After header of ResourceExhausted and
+// Unavailable responses when the status carries no errdetails.RetryInfo.
+const DefaultRetryAfter = time.Second
+
+// defaultHTTPStatus covers every gRPC code.
+var defaultHTTPStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499, // client closed request
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// StatusMapper picks the HTTP status code for a gRPC status.
+// The pb.Error detail code wins when present, then the per service overrides,
+// then the default gRPC code mapping.
+type StatusMapper struct {
+	// services holds overrides keyed by full service name, e.g. "echo.EchoService"
+	services   map[string]map[codes.Code]int
+	retryAfter time.Duration
+}
+
+func NewStatusMapper() *StatusMapper {
+	return &StatusMapper{
+		services:   make(map[string]map[codes.Code]int),
+		retryAfter: DefaultRetryAfter,
+	}
+}
+
+// Override sets the HTTP status used for the gRPC code by the given service.
+func (m *StatusMapper) Override(service string, code codes.Code, httpStatus int) *StatusMapper {
+	overrides, ok := m.services[service]
+	if !ok {
+		overrides = make(map[codes.Code]int)
+		m.services[service] = overrides
+	}
+	overrides[code] = httpStatus
+	return m
+}
+
+// SetRetryAfter sets the fallback Retry-After delay.
+func (m *StatusMapper) SetRetryAfter(d time.Duration) *StatusMapper {
+	m.retryAfter = d
+	return m
+}
+
+// HTTPStatus returns the HTTP status for st. rpcMethod is the full method name
+// in the "/package.service/method" format, it may be empty.
+func (m *StatusMapper) HTTPStatus(rpcMethod string, st *status.Status) int {
+	for _, d := range st.Details() {
+		if e, ok := d.(*pb.Error); ok && validHTTPStatus(int(e.Code)) {
+			return int(e.Code)
+		}
+	}
+	if rpcMethod != "" {
+		service := path.Dir(rpcMethod)[1:]
+		if code, ok := m.services[service][st.Code()]; ok {
+			return code
+		}
+	}
+	if code, ok := defaultHTTPStatus[st.Code()]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// RetryAfter returns the Retry-After header value for st, it is empty for the
+// codes which don't need the header.
+func (m *StatusMapper) RetryAfter(st *status.Status) string {
+	if st.Code() != codes.ResourceExhausted && st.Code() != codes.Unavailable {
+		return ""
+	}
+	delay := m.retryAfter
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+			delay = ri.RetryDelay.AsDuration()
+		}
+	}
+	// Retry-After is in whole seconds, round up
+	seconds := int64((delay + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+func validHTTPStatus(code int) bool {
+	return code >= 100 && code <= 599
+}
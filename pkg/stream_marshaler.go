@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"bytes"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// SSEContentType is the media type of server-sent events.
+const SSEContentType = "text/event-stream"
+
+// NDJSONContentType is the media type of newline delimited JSON.
+const NDJSONContentType = "application/x-ndjson"
+
+// streamError returns the pb.Error of a ForwardResponseStream error chunk.
+// The chunk carries the status returned by the stream error handler, the
+// pb.Error detail is expected to be put there by it.
+func streamError(v interface{}) (*pb.Error, bool) {
+	chunk, ok := v.(map[string]proto.Message)
+	if !ok {
+		return nil, false
+	}
+	st, ok := chunk["error"].(*spb.Status)
+	if !ok {
+		return nil, false
+	}
+	for _, d := range st.Details {
+		errMsg := &pb.Error{}
+		if d.UnmarshalTo(errMsg) == nil {
+			return errMsg, true
+		}
+	}
+	return &pb.Error{Code: 500, Message: st.Message}, true
+}
+
+// streamResult returns the message of a ForwardResponseStream result chunk.
+func streamResult(v interface{}) (interface{}, bool) {
+	chunk, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	res, ok := chunk["result"]
+	return res, ok
+}
+
+// NDJSONMarshaler writes the stream messages as newline delimited JSON,
+// the error chunk in the middle of a stream is {"error": pb.Error}.
+// Everything else is left to the wrapped marshaler.
+type NDJSONMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *NDJSONMarshaler) ContentType(_ interface{}) string {
+	return NDJSONContentType
+}
+
+func (m *NDJSONMarshaler) Marshal(v interface{}) ([]byte, error) {
+	errMsg, ok := streamError(v)
+	if !ok {
+		return m.Marshaler.Marshal(v)
+	}
+	buf, err := m.Marshaler.Marshal(map[string]proto.Message{"error": errMsg})
+	if err != nil {
+		return nil, err
+	}
+	// the gateway writes no delimiter after the error chunk
+	return append(buf, '\n'), nil
+}
+
+func (m *NDJSONMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
+
+// SSEMarshaler writes the stream messages as server-sent events: a message
+// is the data of an unnamed event, the error is the data of an "error" event.
+// Values which are not stream chunks, e.g. the pb.Error of ErrorHandler,
+// are written as plain JSON.
+type SSEMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *SSEMarshaler) ContentType(_ interface{}) string {
+	return SSEContentType
+}
+
+func (m *SSEMarshaler) Marshal(v interface{}) ([]byte, error) {
+	if errMsg, ok := streamError(v); ok {
+		return m.event("error", errMsg, []byte("\n\n"))
+	}
+	if res, ok := streamResult(v); ok {
+		// Delimiter ends the event
+		return m.event("", res, []byte("\n"))
+	}
+	return m.Marshaler.Marshal(v)
+}
+
+func (m *SSEMarshaler) event(name string, data interface{}, end []byte) ([]byte, error) {
+	buf, err := m.Marshaler.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if name != "" {
+		b.WriteString("event: " + name + "\n")
+	}
+	// data can't span lines, JSON newlines are whitespace
+	b.WriteString("data: ")
+	b.Write(bytes.ReplaceAll(buf, []byte("\n"), []byte(" ")))
+	b.Write(end)
+	return b.Bytes(), nil
+}
+
+func (m *SSEMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
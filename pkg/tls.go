@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ClientCertSubjectKey is the metadata key of the verified client certificate subject.
+const ClientCertSubjectKey = "x-client-cert-subject"
+
+// TLSFiles are the PEM files of a TLS endpoint.
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	// CAFile is the bundle which verifies the peer certificates,
+	// the system pool is used when it is empty
+	CAFile string
+	// ClientAuth requires and verifies the client certificates (mTLS)
+	ClientAuth bool
+}
+
+// TLSReloader keeps the certificates loaded from TLSFiles and reloads them
+// when the files change on disk. New handshakes use the reloaded files,
+// established connections are not affected.
+type TLSReloader struct {
+	files TLSFiles
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes []time.Time
+}
+
+func NewTLSReloader(files TLSFiles) (*TLSReloader, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, errors.New("tls cert and key files are required")
+	}
+	if files.ClientAuth && files.CAFile == "" {
+		return nil, errors.New("tls CA file is required to verify client certificates")
+	}
+	r := &TLSReloader{files: files}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files when any of them changed since the last load.
+// It reports whether the files were reloaded.
+func (r *TLSReloader) Reload() (bool, error) {
+	modTimes, err := r.modTimesOnDisk()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	changed := !equalTimes(modTimes, r.modTimes)
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("load tls key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.files.CAFile != "" {
+		pem, err := ioutil.ReadFile(r.files.CAFile)
+		if err != nil {
+			return false, fmt.Errorf("read tls CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("no certificates in tls CA file %s", r.files.CAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.modTimes = &cert, pool, modTimes
+	r.mu.Unlock()
+	return true, nil
+}
+
+func (r *TLSReloader) modTimesOnDisk() ([]time.Time, error) {
+	var res []time.Time
+	for _, name := range []string{r.files.CertFile, r.files.KeyFile, r.files.CAFile} {
+		if name == "" {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fi.ModTime())
+	}
+	return res, nil
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch checks the files every interval until ctx is done.
+// A broken rotation is logged and the previous certificates are kept.
+func (r *TLSReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				log.Println("failed to reload tls certificates:", err)
+			} else if reloaded {
+				log.Println("tls certificates reloaded from", r.files.CertFile)
+			}
+		}
+	}
+}
+
+func (r *TLSReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// ServerConfig returns the config of a listener which serves both HTTP/2
+// (gRPC included) and HTTP/1.1. GetCertificate is set besides
+// GetConfigForClient for http.Server.ServeTLS, which requires one of
+// Certificates and GetCertificate before Go 1.18.
+func (r *TLSReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*cert},
+			}
+			if r.files.ClientAuth {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = pool
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns the config of the gateway connection to the gRPC server.
+// The client certificate follows the reloads, the CA bundle is the one loaded
+// when ClientConfig is called.
+func (r *TLSReloader) ClientConfig(serverName string) *tls.Config {
+	_, pool := r.current()
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		RootCAs:    pool,
+	}
+	if r.files.ClientAuth {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		}
+	}
+	return cfg
+}
+
+// IsGateway reports whether the gRPC peer is the gateway of this process,
+// which dials with the served certificate: the peer presents a verified
+// client certificate with the subject of the current one.
+func (r *TLSReloader) IsGateway(p *peer.Peer) bool {
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return false
+	}
+	cert, _ := r.current()
+	if cert == nil || len(cert.Certificate) == 0 {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	// the subject survives the rotations of the certificate
+	return bytes.Equal(info.State.VerifiedChains[0][0].RawSubject, leaf.RawSubject)
+}
+
+// ClientCertAnnotator forwards the subject of the verified client certificate
+// as ClientCertSubjectKey metadata, register it with runtime.WithMetadata.
+// HeaderMatchers never forward ClientCertSubjectKey from the request headers,
+// and the gRPC server trusts it from the gateway only, see
+// ClientCertUnaryServerInterceptor.
+func ClientCertAnnotator(_ context.Context, r *http.Request) metadata.MD {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return metadata.Pairs(ClientCertSubjectKey, r.TLS.VerifiedChains[0][0].Subject.String())
+}
+
+// trustClientCert replaces the ClientCertSubjectKey metadata of the calls
+// which don't come from the gateway with the subject of the peer certificate,
+// or drops it. The calls without a peer are the ones of the in-process gateway.
+func trustClientCert(ctx context.Context, isGateway func(*peer.Peer) bool) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || (isGateway != nil && isGateway(p)) {
+		return ctx
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	delete(md, ClientCertSubjectKey)
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+		md.Set(ClientCertSubjectKey, info.State.VerifiedChains[0][0].Subject.String())
+	}
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// ClientCertUnaryServerInterceptor makes the ClientCertSubjectKey metadata
+// trustworthy: it is kept on the calls of the gateway, recognized by
+// isGateway, e.g. TLSReloader.IsGateway, and is the subject of the verified
+// peer certificate on the direct calls. A nil isGateway trusts no peer.
+func ClientCertUnaryServerInterceptor(isGateway func(*peer.Peer) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(trustClientCert(ctx, isGateway), req)
+	}
+}
+
+// ClientCertStreamServerInterceptor is the stream version of ClientCertUnaryServerInterceptor.
+func ClientCertStreamServerInterceptor(isGateway func(*peer.Peer) bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = trustClientCert(ss.Context(), isGateway)
+		return handler(srv, wrapped)
+	}
+}
+
+// ClientCertSubject returns the subject of the verified client certificate
+// of the call, behind the gateway the one of the gateway client. It is
+// reliable behind the ClientCert interceptors only.
+func ClientCertSubject(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(ClientCertSubjectKey); len(v) > 0 {
+		return v[0], true
+	}
+	return "", false
+}
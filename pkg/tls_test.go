@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestIncomingDeniesClientCertSubject(t *testing.T) {
+	m := NewHeaderMatchers(HeadersConfig{Incoming: HeaderRules{
+		Allow:  []string{"x-client-*"},
+		Rename: map[string]string{"x-subject": ClientCertSubjectKey},
+	}})
+	for _, key := range []string{
+		"Grpc-Metadata-X-Client-Cert-Subject",
+		"X-Client-Cert-Subject",
+		"X-Subject",
+	} {
+		if h, ok := m.Incoming(key); ok {
+			t.Errorf("Incoming(%q) = %q, want denied", key, h)
+		}
+	}
+}
+
+func peerWithCert(subject string) *peer.Peer {
+	p := &peer.Peer{}
+	if subject != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: subject}}
+		p.AuthInfo = credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}}
+	}
+	return p
+}
+
+func TestTrustClientCert(t *testing.T) {
+	isGateway := func(p *peer.Peer) bool {
+		info, ok := p.AuthInfo.(credentials.TLSInfo)
+		return ok && info.State.VerifiedChains[0][0].Subject.CommonName == "gateway"
+	}
+	tests := []struct {
+		name string
+		peer *peer.Peer
+		want string
+	}{
+		{"gateway", peerWithCert("gateway"), "forged"},
+		{"no peer", nil, "forged"},
+		{"direct client", peerWithCert("client"), "CN=client"},
+		{"direct without certificate", peerWithCert(""), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ClientCertSubjectKey, "forged"))
+			if tt.peer != nil {
+				ctx = peer.NewContext(ctx, tt.peer)
+			}
+			got, _ := ClientCertSubject(trustClientCert(ctx, isGateway))
+			if got != tt.want {
+				t.Errorf("subject = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
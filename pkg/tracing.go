@@ -0,0 +1,267 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TraceIDHeader carries the trace id between the client, the gateway and the gRPC server.
+const TraceIDHeader = "x-trace-id"
+
+const tracerName = "github.com/akhripko/grpc-gateway/pkg"
+
+// propagator handles the W3C traceparent and tracestate headers.
+var propagator = propagation.TraceContext{}
+
+// NewSpanExporter returns the exporter by name: "stdout" writes the spans to w,
+// "none" drops them.
+func NewSpanExporter(name string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", name)
+}
+
+// NewTracerProvider samples every span, exporter may be nil.
+// Spans get valid trace ids even when they are not exported.
+func NewTracerProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithSampler(sdktrace.AlwaysSample())}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+type traceIDKey struct{}
+
+// ContextWithTraceID stores the x-trace-id of the request.
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the x-trace-id of the request, it is empty
+// when the request didn't pass the tracing middleware or interceptor.
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// NewTraceID returns the trace id of the current span, or a random one
+// when there is no span.
+func NewTraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type tracingHandler struct {
+	next   http.Handler
+	tracer trace.Tracer
+}
+
+func (h *tracingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	ctx, route := WithRouteInfo(ctx)
+	ctx, span := h.tracer.Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", "", req)...),
+	)
+	defer span.End()
+
+	// the trace id of the span is used when the client didn't send one,
+	// it reaches the gRPC server as the x-trace-id metadata
+	traceID := req.Header.Get(TraceIDHeader)
+	if traceID == "" {
+		traceID = span.SpanContext().TraceID().String()
+		req.Header.Set(TraceIDHeader, traceID)
+	}
+	ctx = ContextWithTraceID(ctx, traceID)
+
+	res, rw := wrapResponseWriter(w)
+	h.next.ServeHTTP(rw, req.WithContext(ctx))
+
+	if route.Pattern != "" {
+		span.SetName(req.Method + " " + route.Pattern)
+		span.SetAttributes(semconv.HTTPRouteKey.String(route.Pattern))
+	}
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(res.status)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(res.status))
+}
+
+// WithTracingMiddleware starts a server span per request, continuing the
+// trace from the traceparent and tracestate headers. It must wrap the other
+// middlewares to get the trace id into their logs.
+func WithTracingMiddleware(h http.Handler, tp trace.TracerProvider) http.Handler {
+	return &tracingHandler{next: h, tracer: tp.Tracer(tracerName)}
+}
+
+// metadataCarrier adapts metadata.MD to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func rpcAttributes(fullMethod string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.RPCSystemKey.String("grpc"),
+		semconv.RPCServiceKey.String(path.Dir(fullMethod)[1:]),
+		semconv.RPCMethodKey.String(path.Base(fullMethod)),
+	}
+}
+
+func endRPCSpan(span trace.Span, err error) {
+	st, _ := status.FromError(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(st.Code())))
+	if err != nil {
+		span.SetStatus(otelcodes.Error, st.Message())
+	}
+	span.End()
+}
+
+// TracingUnaryServerInterceptor starts a server span per call, continuing the
+// trace from the traceparent metadata.
+func TracingUnaryServerInterceptor(tp trace.TracerProvider) grpc.UnaryServerInterceptor {
+	tracer := tp.Tracer(tracerName)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = propagator.Extract(ctx, metadataCarrier(md))
+		}
+		ctx, span := tracer.Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(rpcAttributes(info.FullMethod)...),
+		)
+		res, err := handler(ctx, req)
+		endRPCSpan(span, err)
+		return res, err
+	}
+}
+
+// TracingUnaryClientInterceptor starts a client span per call and sends it
+// as the traceparent metadata.
+func TracingUnaryClientInterceptor(tp trace.TracerProvider) grpc.UnaryClientInterceptor {
+	tracer := tp.Tracer(tracerName)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcAttributes(method)...),
+		)
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		propagator.Inject(ctx, metadataCarrier(md))
+		err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// TracingStreamServerInterceptor is the stream version of TracingUnaryServerInterceptor.
+func TracingStreamServerInterceptor(tp trace.TracerProvider) grpc.StreamServerInterceptor {
+	tracer := tp.Tracer(tracerName)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = propagator.Extract(ctx, metadataCarrier(md))
+		}
+		ctx, span := tracer.Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(rpcAttributes(info.FullMethod)...),
+		)
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		err := handler(srv, wrapped)
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// tracedClientStream ends the span when the stream is over.
+type tracedClientStream struct {
+	grpc.ClientStream
+	span trace.Span
+	once sync.Once
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.end(nil)
+	} else if err != nil {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *tracedClientStream) end(err error) {
+	s.once.Do(func() {
+		endRPCSpan(s.span, err)
+	})
+}
+
+// TracingStreamClientInterceptor is the stream version of TracingUnaryClientInterceptor.
+// The span ends when RecvMsg reports the end of the stream.
+func TracingStreamClientInterceptor(tp trace.TracerProvider) grpc.StreamClientInterceptor {
+	tracer := tp.Tracer(tracerName)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcAttributes(method)...),
+		)
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		propagator.Inject(ctx, metadataCarrier(md))
+		cs, err := streamer(metadata.NewOutgoingContext(ctx, md), desc, cc, method, opts...)
+		if err != nil {
+			endRPCSpan(span, err)
+			return nil, err
+		}
+		return &tracedClientStream{ClientStream: cs, span: span}, nil
+	}
+}
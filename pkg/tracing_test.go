@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/akhripko/grpc-gateway/api/echo"
+)
+
+// tracedEchoServer sends back the x-trace-id it got, like the trace id
+// interceptor of the server does.
+type tracedEchoServer struct {
+	pb.UnimplementedEchoServiceServer
+}
+
+func (tracedEchoServer) PostEcho(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if id := md.Get(TraceIDHeader); len(id) > 0 {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(TraceIDHeader, id[0]))
+	}
+	return &pb.EchoResponse{Name: req.Name}, nil
+}
+
+func TestTracingContinuesTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	s := grpc.NewServer(grpc.UnaryInterceptor(TracingUnaryServerInterceptor(tp)))
+	pb.RegisterEchoServiceServer(s, tracedEchoServer{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(TracingUnaryClientInterceptor(tp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	headers := NewHeaderMatchers(HeadersConfig{
+		Incoming: HeaderRules{Allow: []string{TraceIDHeader}},
+		Outgoing: HeaderRules{Allow: []string{TraceIDHeader}},
+	})
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headers.Incoming),
+		runtime.WithOutgoingHeaderMatcher(headers.Outgoing),
+	)
+	if err := pb.RegisterEchoServiceHandlerClient(context.Background(), mux, pb.NewEchoServiceClient(conn)); err != nil {
+		t.Fatal(err)
+	}
+	h := WithTracingMiddleware(mux, tp)
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodPost, "/v1/echo/abc", strings.NewReader(`{}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	var rpc *tracetest.SpanStub
+	spans := exporter.GetSpans()
+	for i := range spans {
+		if spans[i].Name == "/echo.EchoService/PostEcho" && spans[i].SpanKind == trace.SpanKindServer {
+			rpc = &spans[i]
+		}
+	}
+	if rpc == nil {
+		t.Fatalf("no gRPC server span in %d spans", len(spans))
+	}
+	if got := rpc.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("gRPC span trace id = %s, want the traceparent one %s", got, traceID)
+	}
+	if got := rec.Header().Get(TraceIDHeader); got != rpc.SpanContext.TraceID().String() {
+		t.Errorf("%s = %q, want the gRPC span trace id %s", TraceIDHeader, got, rpc.SpanContext.TraceID())
+	}
+}
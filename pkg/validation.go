@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/akhripko/grpc-gateway/api/echo/validate"
+)
+
+// patterns caches the compiled validate.FieldRules patterns.
+var patterns sync.Map // string -> *regexp.Regexp
+
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(expr, re)
+	return re, nil
+}
+
+func isRequired(fd protoreflect.FieldDescriptor) bool {
+	behaviors, _ := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	for _, b := range behaviors {
+		if b == annotations.FieldBehavior_REQUIRED {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldRules(fd protoreflect.FieldDescriptor) *validate.FieldRules {
+	rules, _ := proto.GetExtension(fd.Options(), validate.E_Rules).(*validate.FieldRules)
+	return rules
+}
+
+// checkValue returns the violated rule of a single value, empty when it is valid.
+func checkValue(rules *validate.FieldRules, v protoreflect.Value, kind protoreflect.Kind) string {
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n := v.Int()
+		if rules.Min != nil && n < rules.GetMin() {
+			return fmt.Sprintf("must be greater than or equal to %d", rules.GetMin())
+		}
+		if rules.Max != nil && n > rules.GetMax() {
+			return fmt.Sprintf("must be less than or equal to %d", rules.GetMax())
+		}
+	case protoreflect.StringKind:
+		s := v.String()
+		n := uint32(utf8.RuneCountInString(s))
+		if rules.MinLen != nil && n < rules.GetMinLen() {
+			return fmt.Sprintf("must be at least %d characters long", rules.GetMinLen())
+		}
+		if rules.MaxLen != nil && n > rules.GetMaxLen() {
+			return fmt.Sprintf("must be at most %d characters long", rules.GetMaxLen())
+		}
+		if rules.Pattern != "" {
+			re, err := compilePattern(rules.Pattern)
+			if err != nil {
+				return fmt.Sprintf("has an invalid pattern rule: %v", err)
+			}
+			if !re.MatchString(s) {
+				return fmt.Sprintf("must match %q", rules.Pattern)
+			}
+		}
+	}
+	return ""
+}
+
+func validateMessage(m protoreflect.Message, prefix string, violations []*errdetails.BadRequest_FieldViolation) []*errdetails.BadRequest_FieldViolation {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + string(fd.Name())
+		if isRequired(fd) && !m.Has(fd) {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: name, Description: "is required"})
+			continue
+		}
+		if !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+
+		if rules := fieldRules(fd); rules != nil {
+			if fd.IsList() {
+				list := v.List()
+				for j := 0; j < list.Len(); j++ {
+					if desc := checkValue(rules, list.Get(j), fd.Kind()); desc != "" {
+						violations = append(violations, &errdetails.BadRequest_FieldViolation{
+							Field:       fmt.Sprintf("%s[%d]", name, j),
+							Description: desc,
+						})
+					}
+				}
+			} else if !fd.IsMap() {
+				if desc := checkValue(rules, v, fd.Kind()); desc != "" {
+					violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: name, Description: desc})
+				}
+			}
+		}
+
+		// the rules of the nested messages apply as well
+		if fd.Message() == nil || fd.IsMap() {
+			continue
+		}
+		if fd.IsList() {
+			list := v.List()
+			for j := 0; j < list.Len(); j++ {
+				violations = validateMessage(list.Get(j).Message(), fmt.Sprintf("%s[%d].", name, j), violations)
+			}
+		} else {
+			violations = validateMessage(v.Message(), name+".", violations)
+		}
+	}
+	return violations
+}
+
+// Validate checks the google.api.field_behavior REQUIRED fields and the
+// echo.validate.rules of the message and its nested messages. The error is
+// InvalidArgument with an errdetails.BadRequest listing every violation,
+// the fields are named by their proto path, e.g. "em_id.id" or "data2[1]".
+func Validate(m proto.Message) error {
+	violations := validateMessage(m.ProtoReflect(), "", nil)
+	if len(violations) == 0 {
+		return nil
+	}
+	st, err := status.New(codes.InvalidArgument, "request validation failed").
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "request validation failed")
+	}
+	return st.Err()
+}
+
+// ValidationUnaryServerInterceptor rejects the requests failing Validate
+// before they reach the handler.
+func ValidationUnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if m, ok := req.(proto.Message); ok {
+		if err := Validate(m); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// validatingServerStream validates every received message.
+type validatingServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if msg, ok := m.(proto.Message); ok {
+		return Validate(msg)
+	}
+	return nil
+}
+
+// ValidationStreamServerInterceptor is the stream version of ValidationUnaryServerInterceptor,
+// the handler gets the validation error from RecvMsg.
+func ValidationStreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingServerStream{ServerStream: ss})
+}
@@ -0,0 +1,198 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// streamRoute is the HTTP binding of a client or bidi streaming method.
+type streamRoute struct {
+	method string
+	path   pathTemplate
+}
+
+// clientStreamRoutes collects the HTTP bindings of the client and bidi
+// streaming methods of the registered services.
+func clientStreamRoutes() []streamRoute {
+	var routes []streamRoute
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				md := methods.Get(j)
+				if !md.IsStreamingClient() {
+					continue
+				}
+				rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+				if !ok || rule == nil {
+					continue
+				}
+				for _, r := range append([]*annotations.HttpRule{rule}, rule.AdditionalBindings...) {
+					method, tmpl := httpRuleBinding(r)
+					// protoc-gen-grpc-gateway rejects the invalid templates
+					if path, err := parsePathTemplate(tmpl); err == nil {
+						routes = append(routes, streamRoute{method: method, path: path})
+					}
+				}
+			}
+		}
+		return true
+	})
+	return routes
+}
+
+// wsResponseWriter turns the newline delimited gateway stream into
+// WebSocket text frames, one frame per message.
+type wsResponseWriter struct {
+	conn   *websocket.Conn
+	header http.Header
+	status int
+	buf    bytes.Buffer
+	err    error
+}
+
+func (w *wsResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *wsResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *wsResponseWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf.Write(b)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		line := w.buf.Next(i + 1)
+		if err := w.send(line[:i]); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Flush is a no-op, the messages are sent as soon as they are complete.
+func (w *wsResponseWriter) Flush() {}
+
+func (w *wsResponseWriter) send(msg []byte) error {
+	if len(bytes.TrimSpace(msg)) == 0 {
+		return nil
+	}
+	if err := w.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// close sends the rest of the response, e.g. the error written by
+// ErrorHandler, and closes the WebSocket.
+func (w *wsResponseWriter) close() {
+	if w.err != nil {
+		return
+	}
+	_ = w.send(w.buf.Bytes())
+	code, text := websocket.CloseNormalClosure, ""
+	if w.status >= http.StatusBadRequest {
+		code, text = websocket.CloseInternalServerErr, http.StatusText(w.status)
+		if w.status < http.StatusInternalServerError {
+			code = websocket.ClosePolicyViolation
+		}
+	}
+	_ = w.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, text))
+}
+
+type wsProxy struct {
+	next     http.Handler
+	upgrader websocket.Upgrader
+	routes   []streamRoute
+	logger   Logger
+}
+
+func (p *wsProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		p.next.ServeHTTP(w, r)
+		return
+	}
+	var route *streamRoute
+	for i := range p.routes {
+		if p.routes[i].path.match(r.URL.Path) {
+			route = &p.routes[i]
+			break
+		}
+	}
+	if route == nil {
+		p.next.ServeHTTP(w, r)
+		return
+	}
+
+	conn, err := p.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has replied already
+		p.logger.Error("websocket upgrade failed", "uri", r.RequestURI, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// every text frame is a request message of the stream
+	body, bodyWriter := io.Pipe()
+	go func() {
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					err = nil
+				}
+				// nil closes the request stream with io.EOF
+				_ = bodyWriter.CloseWithError(err)
+				return
+			}
+			if _, err := bodyWriter.Write(append(msg, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+
+	// the upgrade headers pass the IncomingHeaderMatcher of the mux as usual
+	req := r.Clone(r.Context())
+	req.Method = route.method
+	req.Body = body
+	req.ContentLength = -1
+	for _, h := range []string{"Connection", "Upgrade", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol"} {
+		req.Header.Del(h)
+	}
+	// a message per line, the error chunk included
+	req.Header.Set("Accept", NDJSONContentType)
+	res := &wsResponseWriter{conn: conn, header: make(http.Header)}
+	p.next.ServeHTTP(res, req)
+	res.close()
+	_ = body.Close()
+}
+
+// WithWebSocketProxy serves the client and bidi streaming routes of h over
+// WebSockets: each text frame is a JSON request message and each response
+// message is sent back as a text frame. Other requests go to h unchanged.
+// checkOrigin may be nil to allow the same origin only.
+func WithWebSocketProxy(h http.Handler, logger Logger, checkOrigin func(r *http.Request) bool) http.Handler {
+	return &wsProxy{
+		next:     h,
+		upgrader: websocket.Upgrader{CheckOrigin: checkOrigin},
+		routes:   clientStreamRoutes(),
+		logger:   logger,
+	}
+}